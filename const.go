@@ -2,8 +2,10 @@ package soda
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
 )
 
 var (
@@ -19,25 +21,39 @@ var (
 
 // parameter props.
 const (
-	propExplode = "explode"
-	propStyle   = "style"
+	propExplode  = "explode"
+	propStyle    = "style"
+	propFrom     = "from"
+	propJoinWith = "joinWith"
+	propContent  = "content"
 )
 
+// input sources.
+const (
+	fromBearer = "bearer"
+)
+
+// BearerSecurityName is the name of the security scheme documenting the bearer tokens bound with `oai:"from=bearer"`.
+const BearerSecurityName = "bearerAuth"
+
 // schema props.
 const (
 	// generic properties.
-	propTitle           = "title"
-	propDescription     = "description"
-	propType            = "type"
-	propDeprecated      = "deprecated"
-	propAllowEmptyValue = "allowEmptyValue"
-	propNullable        = "nullable"
-	propReadOnly        = "readOnly"
-	propWriteOnly       = "writeOnly"
-	propEnum            = "enum"
-	propDefault         = "default"
-	propExample         = "example"
-	propRequired        = "required"
+	propTitle            = "title"
+	propDescription      = "description"
+	propType             = "type"
+	propDeprecated       = "deprecated"
+	propDeprecatedReason = "deprecatedReason"
+	propAllowEmptyValue  = "allowEmptyValue"
+	propAllowEmpty       = "allowEmpty"
+	propNullable         = "nullable"
+	propReadOnly         = "readOnly"
+	propWriteOnly        = "writeOnly"
+	propEnum             = "enum"
+	propDefault          = "default"
+	propExample          = "example"
+	propRequired         = "required"
+	propSchemaOf         = "schemaOf"
 	// string specified properties.
 	propMinLength = "minLength"
 	propMaxLength = "maxLength"
@@ -55,6 +71,52 @@ const (
 	propMinItems    = "minItems"
 	propMaxItems    = "maxItems"
 	propUniqueItems = "uniqueItems"
+	// object specified properties.
+	propPropertyNamesPattern = "propertyNamesPattern"
+	propAdditionalProperties = "additionalProperties"
+)
+
+// schema extensions.
+const (
+	extPrefix           = "x-"
+	extFormatMinimum    = "x-formatMinimum"
+	extFormatMaximum    = "x-formatMaximum"
+	extPropertyNames    = "x-propertyNames"
+	extSuccess          = "x-success"
+	extDisplayOrder     = "x-displayOrder"
+	extDeprecatedReason = "x-deprecated-reason"
+)
+
+// string formats.
+const (
+	formatDate     = "date"
+	formatDateTime = "date-time"
+	formatBinary   = "binary"
+)
+
+// media types.
+const (
+	mediaTypeNDJSON = "application/x-ndjson"
+)
+
+// bodyOptional marks the request body as optional, e.g. `body:"json,optional"`.
+const bodyOptional = "optional"
+
+// mediaTypeAliases maps the short aliases of the media types to their media type, e.g. `body:"json,form"`.
+var mediaTypeAliases = map[string]string{
+	"json":      fiber.MIMEApplicationJSON,
+	"xml":       fiber.MIMEApplicationXML,
+	"form":      fiber.MIMEApplicationForm,
+	"multipart": fiber.MIMEMultipartForm,
+}
+
+// HeaderIdempotencyKey is the header carrying the idempotency key of a request.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// The headers sent with the responses of the operations deprecated with a sunset date.
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
 )
 
 type ck string
@@ -75,4 +137,15 @@ const (
 var (
 	regexOperationID = regexp.MustCompile("[^a-zA-Z0-9]+")
 	regexSchemaName  = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+	// regexTypeArg matches the type arguments qualified by their import path, e.g. example.com/pkg.User in Page[example.com/pkg.User].
+	regexTypeArg       = regexp.MustCompile(`[^\[\],*]*/[^\[\],]*`)
+	regexJSONPCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+	regexFiberParam    = regexp.MustCompile(`:([a-zA-Z0-9_]+)(?:<[^>]*>)?\??`)
+	regexUUID          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 )
+
+// genericNameReplacer flattens the type arguments of the generic type names, e.g. Page[pkg.User] into Page-pkg.User.
+var genericNameReplacer = strings.NewReplacer("[", "-", ",", "-", "]", "")
+
+// maxSchemaDepth is the maximum nesting of the types walked to generate a schema.
+const maxSchemaDepth = 64
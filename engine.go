@@ -1,6 +1,23 @@
 package soda
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gofiber/fiber/v2"
 	"gopkg.in/yaml.v3"
@@ -11,8 +28,22 @@ type Engine struct {
 	app            *fiber.App
 	cachedSpecYAML []byte
 	cachedSpecJSON []byte
+	cachedVersion  uint64
+	specMu         sync.Mutex
+
+	specHooks     []func(*openapi3.T)
+	specHooksOnce sync.Once
+
+	serverVariableEnvs []serverVariableEnv
+
+	// validated is set once the spec of cachedVersion is validated for WithValidateOnServe, with validateErr.
+	validated   bool
+	validateErr error
 }
 
+// OpenAPI returns the live document the routes are registered into, e.g. to assert on it in tests.
+// It is complete once all the routes are registered. The OnSpecBuilt hooks and the server variables
+// bound to the environment are applied to it only when the spec is first served.
 func (e *Engine) OpenAPI() *openapi3.T {
 	return e.gen.doc
 }
@@ -21,7 +52,450 @@ func (e *Engine) App() *fiber.App {
 	return e.app
 }
 
+// WalkOperations calls fn for every documented operation, ordered by path and method,
+// so that the operations can be post-processed after registration.
+func (e *Engine) WalkOperations(fn func(method, path string, op *openapi3.Operation)) *Engine {
+	paths := e.gen.doc.Paths.Map()
+	patterns := make([]string, 0, len(paths))
+	for pattern := range paths {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+
+	for _, pattern := range patterns {
+		operations := paths[pattern].Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+		for _, method := range methods {
+			fn(method, pattern, operations[method])
+		}
+	}
+	return e
+}
+
+// ServerVariable is a variable substituted in the URL template of a server, e.g. {port} in https://example.com:{port}.
+type ServerVariable struct {
+	Name        string
+	Default     string
+	Enum        []string
+	Description string
+	// Env is the environment variable overriding the default value, resolved when the spec is built.
+	Env string
+}
+
+// AddServer adds a server to the spec, so that the "try it out" feature of the UIs targets the right base URL.
+func (e *Engine) AddServer(url string, description string, variables ...ServerVariable) *Engine {
+	server := &openapi3.Server{URL: url, Description: description}
+	if len(variables) > 0 {
+		server.Variables = make(map[string]*openapi3.ServerVariable, len(variables))
+		for _, v := range variables {
+			variable := &openapi3.ServerVariable{
+				Default:     v.Default,
+				Enum:        v.Enum,
+				Description: v.Description,
+			}
+			server.Variables[v.Name] = variable
+			if v.Env != "" {
+				e.serverVariableEnvs = append(e.serverVariableEnvs, serverVariableEnv{
+					variable: variable,
+					env:      v.Env,
+					fallback: v.Default,
+				})
+			}
+		}
+	}
+	e.gen.doc.Servers = append(e.gen.doc.Servers, server)
+	e.RefreshSpec()
+	return e
+}
+
+// serverVariableEnv binds a server variable to the environment variable overriding its default value.
+type serverVariableEnv struct {
+	variable *openapi3.ServerVariable
+	env      string
+	fallback string
+}
+
+// resolveServerVariables sets the default value of the server variables from their environment variable.
+func (e *Engine) resolveServerVariables() {
+	for _, v := range e.serverVariableEnvs {
+		v.variable.Default = v.fallback
+		if value, ok := os.LookupEnv(v.env); ok {
+			v.variable.Default = value
+		}
+	}
+}
+
+// OnSpecBuilt registers a hook post-processing the spec, e.g. to sort the tags or rewrite the server URLs.
+// The hooks run once in registration order, lazily before the spec is first marshaled,
+// so they must be registered before the spec is first served.
+func (e *Engine) OnSpecBuilt(hook func(*openapi3.T)) *Engine {
+	e.specHooks = append(e.specHooks, hook)
+	return e
+}
+
+// runSpecHooks runs the hooks registered with OnSpecBuilt, once.
+func (e *Engine) runSpecHooks() {
+	e.specHooksOnce.Do(func() {
+		for _, hook := range e.specHooks {
+			hook(e.gen.doc)
+		}
+	})
+}
+
+// buildSpecJSON builds the spec JSON, running the spec hooks and resolving the server variables.
+func (e *Engine) buildSpecJSON() []byte {
+	e.runSpecHooks()
+	e.resolveServerVariables()
+	spec, _ := e.gen.doc.MarshalJSON()
+	return spec
+}
+
+// buildSpecYAML builds the spec YAML, running the spec hooks and resolving the server variables.
+func (e *Engine) buildSpecYAML() []byte {
+	e.runSpecHooks()
+	e.resolveServerVariables()
+	spec, _ := yaml.Marshal(e.gen.doc)
+	return spec
+}
+
+// specJSON returns the cached spec JSON, building it on the first call and after the operations changed.
+func (e *Engine) specJSON() []byte {
+	e.specMu.Lock()
+	defer e.specMu.Unlock()
+	e.checkSpecVersion()
+	if e.cachedSpecJSON == nil {
+		e.cachedSpecJSON = e.buildSpecJSON()
+	}
+	return e.cachedSpecJSON
+}
+
+// freshSpecJSON builds the spec JSON bypassing the cache, with the spec lock held.
+func (e *Engine) freshSpecJSON() []byte {
+	e.specMu.Lock()
+	defer e.specMu.Unlock()
+	return e.buildSpecJSON()
+}
+
+// specYAML returns the cached spec YAML, building it on the first call and after the operations changed.
+func (e *Engine) specYAML() []byte {
+	e.specMu.Lock()
+	defer e.specMu.Unlock()
+	e.checkSpecVersion()
+	if e.cachedSpecYAML == nil {
+		e.cachedSpecYAML = e.buildSpecYAML()
+	}
+	return e.cachedSpecYAML
+}
+
+// checkSpecVersion drops the cached specs if operations were added or removed since they were built.
+// It must be called with the spec lock held.
+func (e *Engine) checkSpecVersion() {
+	if version := e.gen.version.Load(); version != e.cachedVersion {
+		e.cachedVersion = version
+		e.cachedSpecJSON = nil
+		e.cachedSpecYAML = nil
+		e.validated = false
+	}
+}
+
+// RefreshSpec drops the cached specs, so that they are rebuilt when next served.
+// The operations added or removed are picked up automatically,
+// it is needed after changing the document returned by OpenAPI directly.
+func (e *Engine) RefreshSpec() {
+	e.specMu.Lock()
+	defer e.specMu.Unlock()
+	e.cachedSpecJSON = nil
+	e.cachedSpecYAML = nil
+	e.validated = false
+}
+
+// RemoveOperation removes the operation of the given method and path, e.g. "/users/:id", from the spec,
+// releasing its operation ID so that it can be registered again. The path is removed once it has no operation left.
+// Fiber does not support removing routes, so the handlers stay registered on the app.
+func (e *Engine) RemoveOperation(method, path string) *Engine {
+	path = cleanPath(path)
+	item := e.gen.doc.Paths.Value(path)
+	if item == nil {
+		return e
+	}
+	method = strings.ToUpper(method)
+	operation := item.GetOperation(method)
+	if operation == nil {
+		return e
+	}
+	delete(e.gen.operationIDs, operation.OperationID)
+	item.SetOperation(method, nil)
+	e.gen.version.Add(1)
+	if len(item.Operations()) == 0 {
+		e.gen.doc.Paths.Delete(path)
+	}
+	return e
+}
+
+// DefineRequestBody registers a reusable request body for the model in the components,
+// which operations can reference with OperationBuilder.SetRequestBodyRef.
+func (e *Engine) DefineRequestBody(name string, mediaType string, model any) *Engine {
+	schema := e.gen.generateSchemaRef(nil, reflect.TypeOf(model), nameTagOf(mediaType))
+	requestBody := openapi3.NewRequestBody().
+		WithRequired(true).
+		WithContent(openapi3.NewContentWithSchemaRef(schema, []string{mediaType}))
+	e.gen.doc.Components.RequestBodies[name] = &openapi3.RequestBodyRef{Value: requestBody}
+	return e
+}
+
+// RegisterResponse registers a reusable JSON response for the model in the components,
+// which operations can reference with OperationBuilder.AddResponseRef instead of repeating it inline.
+// The status code provides the default description.
+func (e *Engine) RegisterResponse(name string, code int, model any, description string) *Engine {
+	response := e.gen.GenerateResponse(code, model, fiber.MIMEApplicationJSON, description)
+	e.gen.doc.Components.Responses[name] = &openapi3.ResponseRef{Value: response}
+	return e
+}
+
+// SetDefaultErrorResponse registers a JSON error response in the components, named after the status text, e.g. BadRequest,
+// and references it from every operation which does not define a response for the status code,
+// including the responses added to the routers.
+func (e *Engine) SetDefaultErrorResponse(code int, model any, description string) *Engine {
+	name := strings.ReplaceAll(http.StatusText(code), " ", "")
+	if name == "" {
+		name = "Status" + strconv.Itoa(code)
+	}
+	e.RegisterResponse(name, code, model, description)
+	e.gen.defaultResponses[code] = name
+
+	// the operations already registered get the default response as well
+	e.WalkOperations(func(_, _ string, op *openapi3.Operation) {
+		e.gen.addDefaultResponses(op)
+	})
+	e.gen.version.Add(1)
+	return e
+}
+
+// Validate validates the generated spec, returning a *SpecError identifying the offending operation if any.
+// The fiber paths, e.g. /users/:id, are validated as their template, e.g. /users/{id}.
+func (e *Engine) Validate() error {
+	return e.validate(true)
+}
+
+// validate validates the generated spec, the info block is left unchecked unless checkInfo is set.
+func (e *Engine) validate(checkInfo bool) error {
+	ctx := context.Background()
+	doc := *e.gen.doc
+	doc.Paths = openapi3.NewPaths()
+	if !checkInfo {
+		// the engines created without WithInfo have no title nor version
+		doc.Info = &openapi3.Info{Title: "-", Version: "-"}
+	}
+
+	var failed error
+	e.WalkOperations(func(method, path string, op *openapi3.Operation) {
+		template := toTemplatePath(path)
+		item := doc.Paths.Value(template)
+		if item == nil {
+			item = &openapi3.PathItem{Parameters: e.gen.doc.Paths.Value(path).Parameters}
+			doc.Paths.Set(template, item)
+		}
+		item.SetOperation(method, op)
+
+		single := &openapi3.PathItem{Parameters: item.Parameters}
+		single.SetOperation(method, op)
+		if err := openapi3.NewPaths(openapi3.WithPath(template, single)).Validate(ctx); err != nil && failed == nil {
+			failed = &SpecError{Method: method, Path: path, Err: err}
+		}
+	})
+	if failed != nil {
+		return failed
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return &SpecError{Err: err}
+	}
+	return nil
+}
+
+// SubSpec returns a copy of the spec documenting only the operations tagged with the given tag.
+// The components are shared with the spec, so that the references of the operations resolve.
+func (e *Engine) SubSpec(tag string) *openapi3.T {
+	doc := *e.gen.doc
+	doc.Paths = openapi3.NewPaths()
+	doc.Tags = nil
+	if t := e.gen.doc.Tags.Get(tag); t != nil {
+		doc.Tags = openapi3.Tags{t}
+	}
+	e.WalkOperations(func(method, path string, op *openapi3.Operation) {
+		if !slices.Contains(op.Tags, tag) {
+			return
+		}
+		item := doc.Paths.Value(path)
+		if item == nil {
+			item = &openapi3.PathItem{Parameters: e.gen.doc.Paths.Value(path).Parameters}
+			doc.Paths.Set(path, item)
+		}
+		item.SetOperation(method, op)
+	})
+	return &doc
+}
+
+// operationTags returns the sorted tags of the documented operations.
+func (e *Engine) operationTags() []string {
+	var tags []string
+	e.WalkOperations(func(_, _ string, op *openapi3.Operation) {
+		for _, tag := range op.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	})
+	slices.Sort(tags)
+	return tags
+}
+
+// specShard describes a sub-spec in the index served by ServeSpecSplit.
+type specShard struct {
+	Tag string `json:"tag"`
+	URL string `json:"url"`
+}
+
+// ServeSpecSplit serves the spec sharded by tag, so that the UIs of very large APIs load smaller documents.
+// The index of the shards is served at pattern, and the sub-spec of each tag at pattern/{tag}.json.
+func (e *Engine) ServeSpecSplit(pattern string) *Engine {
+	base := strings.TrimSuffix(pattern, "/")
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		e.specMu.Lock()
+		e.runSpecHooks()
+		tags := e.operationTags()
+		e.specMu.Unlock()
+		shards := make([]specShard, 0, len(tags))
+		for _, tag := range tags {
+			shards = append(shards, specShard{Tag: tag, URL: base + "/" + url.PathEscape(tag) + ".json"})
+		}
+		return c.JSON(fiber.Map{"specs": shards})
+	})
+	e.app.Get(base+"/:tag.json", func(c *fiber.Ctx) error {
+		tag, err := url.PathUnescape(c.Params("tag"))
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+		e.specMu.Lock()
+		e.runSpecHooks()
+		if !slices.Contains(e.operationTags(), tag) {
+			e.specMu.Unlock()
+			return fiber.ErrNotFound
+		}
+		e.resolveServerVariables()
+		spec, err := e.SubSpec(tag).MarshalJSON()
+		e.specMu.Unlock()
+		if err != nil {
+			return err
+		}
+		c.Context().SetContentType("application/json; charset=utf-8")
+		return c.Send(spec)
+	})
+	return e
+}
+
+// schemaReport describes a component schema in the report of ServeDebugSchemas.
+type schemaReport struct {
+	Name  string   `json:"name"`
+	Ref   string   `json:"ref"`
+	Refs  []string `json:"refs,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// ServeDebugSchemas serves a report of the component schemas, their references,
+// and the references that fail to dereference, to diagnose the generation issues.
+func (e *Engine) ServeDebugSchemas(pattern string) *Engine {
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		schemas := e.gen.doc.Components.Schemas
+		names := make([]string, 0, len(schemas))
+		for name := range schemas {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		reports := make([]schemaReport, 0, len(names))
+		for _, name := range names {
+			report := schemaReport{Name: name, Ref: "#/components/schemas/" + name}
+			if schemas[name].Value != nil {
+				report.Refs = collectSchemaRefs(schemas[name].Value, nil, nil)
+			}
+			for _, ref := range append([]string{report.Ref}, report.Refs...) {
+				if err := e.checkSchemaRef(ref); err != nil {
+					report.Error = err.Error()
+					break
+				}
+			}
+			reports = append(reports, report)
+		}
+		return c.JSON(fiber.Map{"schemas": reports})
+	})
+	return e
+}
+
+// checkSchemaRef dereferences the schema reference, returning the failure of derefSchema as an error.
+func (e *Engine) checkSchemaRef(ref string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = toError(r)
+		}
+	}()
+	derefSchema(e.gen.doc, openapi3.NewSchemaRef(ref, nil))
+	return nil
+}
+
+// collectSchemaRefs collects the sorted references of the schemas nested in the given schema.
+func collectSchemaRefs(schema *openapi3.Schema, visited []*openapi3.Schema, refs []string) []string {
+	if schema == nil || slices.Contains(visited, schema) {
+		return refs
+	}
+	visited = append(visited, schema)
+
+	nested := make([]*openapi3.SchemaRef, 0, len(schema.Properties)+2)
+	for _, property := range schema.Properties {
+		nested = append(nested, property)
+	}
+	nested = append(nested, schema.Items, schema.AdditionalProperties.Schema, schema.Not)
+	nested = append(nested, schema.AllOf...)
+	nested = append(nested, schema.AnyOf...)
+	nested = append(nested, schema.OneOf...)
+	for _, ref := range nested {
+		if ref == nil {
+			continue
+		}
+		if ref.Ref != "" {
+			if !slices.Contains(refs, ref.Ref) {
+				refs = append(refs, ref.Ref)
+			}
+			continue
+		}
+		refs = collectSchemaRefs(ref.Value, visited, refs)
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// RegisterSchemaOf registers a model under the given name,
+// so that interface fields tagged with `oai:"schemaOf=name"` document the model's schema.
+func (e *Engine) RegisterSchemaOf(name string, model any) *Engine {
+	e.gen.RegisterSchemaOf(name, model)
+	return e
+}
+
+// RegisterTypeName names the component schema of the given type, overriding the reflected name.
+func (e *Engine) RegisterTypeName(t reflect.Type, name string) *Engine {
+	e.gen.RegisterTypeName(t, name)
+	return e
+}
+
+// ServeDocUI serves the UI rendering the spec. The UIs configured with WithUIAssets are served with their assets,
+// as with ServeDocUIAssets, the others load them from their CDN.
 func (e *Engine) ServeDocUI(pattern string, ui UIRender) *Engine {
+	if embedded, ok := ui.(EmbeddedUIRender); ok && embedded.Assets() != nil {
+		return e.ServeDocUIAssets(pattern, embedded)
+	}
 	e.app.Get(pattern, func(c *fiber.Ctx) error {
 		c.Context().SetContentType("text/html; charset=utf-8")
 		return c.SendString(ui.Render(e.gen.doc))
@@ -29,38 +503,199 @@ func (e *Engine) ServeDocUI(pattern string, ui UIRender) *Engine {
 	return e
 }
 
+// ServeDocUIAssets serves the UI with the assets provided by the application, configured with WithUIAssets,
+// under pattern/assets instead of from a CDN, so that the documentation works in air-gapped environments.
+// It panics if the UI has no assets, soda does not bundle them.
+func (e *Engine) ServeDocUIAssets(pattern string, ui EmbeddedUIRender) *Engine {
+	assets := ui.Assets()
+	if assets == nil {
+		panic("the ui has no assets, configure it with WithUIAssets")
+	}
+	base := strings.TrimSuffix(pattern, "/") + "/assets"
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		c.Context().SetContentType("text/html; charset=utf-8")
+		return c.SendString(ui.RenderWithAssets(e.gen.doc, base))
+	})
+	e.app.Get(base+"/*", func(c *fiber.Ctx) error {
+		name := c.Params("*")
+		content, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+		c.Type(strings.TrimPrefix(path.Ext(name), "."))
+		return c.Send(content)
+	})
+	return e
+}
+
+// ServeReDocStandalone serves the Redoc UI with its assets pinned to the given version, e.g. "v2.1.5".
+func (e *Engine) ServeReDocStandalone(pattern string, version string) *Engine {
+	return e.ServeDocUI(pattern, NewRedoc(WithUIVersion(version)))
+}
+
 func (e *Engine) ServeSpecJSON(pattern string) *Engine {
-	if e.cachedSpecJSON == nil {
-		e.cachedSpecJSON, _ = e.gen.doc.MarshalJSON()
+	e.app.Get(pattern, e.sendSpecJSON)
+	return e
+}
+
+// sendSpecJSON sends the spec JSON, validating it first if WithValidateOnServe is set.
+func (e *Engine) sendSpecJSON(c *fiber.Ctx) error {
+	if err := e.validateServedSpec(); err != nil {
+		return err
+	}
+	c.Context().SetContentType("application/json; charset=utf-8")
+	return c.Send(e.specJSON())
+}
+
+// validateServedSpec validates the spec if WithValidateOnServe is set, once per version of the spec.
+// The info block is not validated, so that the engines created without WithInfo can be served.
+func (e *Engine) validateServedSpec() error {
+	if !e.gen.validateOnServe {
+		return nil
 	}
+	e.specMu.Lock()
+	defer e.specMu.Unlock()
+	e.checkSpecVersion()
+	if !e.validated {
+		e.validateErr = e.validate(false)
+		e.validated = true
+	}
+	if e.validateErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, e.validateErr.Error())
+	}
+	return nil
+}
+
+// ServeSpec serves the spec at pattern, pattern.json and pattern.yaml from a single handler.
+// The format is picked by the suffix of the request path, or else by the Accept header, defaulting to JSON.
+func (e *Engine) ServeSpec(pattern string) *Engine {
+	handler := func(c *fiber.Ctx) error {
+		isYAML := strings.HasSuffix(c.Path(), ".yaml")
+		if !isYAML && !strings.HasSuffix(c.Path(), ".json") {
+			accepted := c.Accepts(fiber.MIMEApplicationJSON, "application/yaml", "text/yaml")
+			isYAML = accepted == "application/yaml" || accepted == "text/yaml"
+		}
+		if !isYAML {
+			return e.sendSpecJSON(c)
+		}
+		if err := e.validateServedSpec(); err != nil {
+			return err
+		}
+		c.Context().SetContentType("text/yaml; charset=utf-8")
+		return c.Send(e.specYAML())
+	}
+	e.app.Get(pattern, handler)
+	e.app.Get(pattern+".json", handler)
+	e.app.Get(pattern+".yaml", handler)
+	return e
+}
+
+// specWatchInterval is the interval at which ServeSpecWatch checks the spec for changes.
+var specWatchInterval = time.Second
+
+// ServeSpecWatch serves the spec JSON rebuilt on every request, bypassing the cache, so that it reflects the routes
+// registered since, e.g. during development. The changes are notified as server-sent events at pattern/events,
+// carrying the hash of the new spec, so that the UIs can reload.
+func (e *Engine) ServeSpecWatch(pattern string) *Engine {
 	e.app.Get(pattern, func(c *fiber.Ctx) error {
 		c.Context().SetContentType("application/json; charset=utf-8")
-		return c.Send(e.cachedSpecJSON)
+		return c.Send(e.freshSpecJSON())
+	})
+	e.app.Get(strings.TrimSuffix(pattern, "/")+"/events", func(c *fiber.Ctx) error {
+		c.Context().SetContentType("text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ticker := time.NewTicker(specWatchInterval)
+			defer ticker.Stop()
+			var last string
+			for ; ; <-ticker.C {
+				sum := sha256.Sum256(e.freshSpecJSON())
+				hash := hex.EncodeToString(sum[:])
+				// a heartbeat is sent when the spec is unchanged, so that the disconnected clients are detected
+				event := ": ping\n\n"
+				if hash != last {
+					last = hash
+					event = fmt.Sprintf("event: spec\ndata: %s\n\n", hash)
+				}
+				if _, err := w.WriteString(event); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+		return nil
 	})
 	return e
 }
 
-func (e *Engine) ServeSpecYAML(pattern string) *Engine {
-	if e.cachedSpecYAML == nil {
-		spec, _ := yaml.Marshal(e.gen.doc)
-		e.cachedSpecYAML = spec
+// ServeSpecJSONProtected serves the spec JSON to the requests accepted by authFn, the others get a 401 Unauthorized.
+func (e *Engine) ServeSpecJSONProtected(pattern string, authFn func(*fiber.Ctx) bool) *Engine {
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		if !authFn(c) {
+			return fiber.ErrUnauthorized
+		}
+		return e.sendSpecJSON(c)
+	})
+	return e
+}
+
+// ServeSpecJSONP serves the spec JSON wrapped in the callback given by the callbackParam query parameter.
+// Requests without a callback get the plain spec JSON.
+func (e *Engine) ServeSpecJSONP(pattern string, callbackParam string) *Engine {
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		callback := c.Query(callbackParam)
+		if callback == "" {
+			return e.sendSpecJSON(c)
+		}
+		if !regexJSONPCallback.MatchString(callback) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid callback name")
+		}
+		if err := e.validateServedSpec(); err != nil {
+			return err
+		}
+		c.Context().SetContentType("application/javascript; charset=utf-8")
+		return c.SendString("/**/" + callback + "(" + string(e.specJSON()) + ");")
+	})
+	return e
+}
+
+// ServeJSONSchema serves a JSON Schema (draft 2020-12) document defining the given models under `$defs`,
+// for the tools consuming plain JSON Schema rather than OpenAPI. The schemas are generated with the options of the engine,
+// as in the spec. It panics if a model is not a named type.
+func (e *Engine) ServeJSONSchema(pattern string, models ...any) *Engine {
+	document, err := generateJSONSchemaDefs(e.gen, models)
+	if err != nil {
+		panic(err)
 	}
 	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		c.Context().SetContentType("application/schema+json; charset=utf-8")
+		return c.Send(document)
+	})
+	return e
+}
+
+func (e *Engine) ServeSpecYAML(pattern string) *Engine {
+	e.app.Get(pattern, func(c *fiber.Ctx) error {
+		if err := e.validateServedSpec(); err != nil {
+			return err
+		}
 		c.Context().SetContentType("text/yaml; charset=utf-8")
-		return c.Send(e.cachedSpecYAML)
+		return c.Send(e.specYAML())
 	})
 	return e
 }
 
-func New() *Engine {
-	return NewWith(fiber.New())
+func New(opts ...Option) *Engine {
+	return NewWith(fiber.New(), opts...)
 }
 
-func NewWith(app *fiber.App) *Engine {
+func NewWith(app *fiber.App, opts ...Option) *Engine {
 	return &Engine{
 		app: app,
 		Router: &Router{
-			gen: NewGenerator(),
+			gen: NewGenerator(opts...),
 			Raw: app,
 		},
 	}
@@ -1,8 +1,18 @@
 package soda_test
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gofiber/fiber/v2"
@@ -28,6 +38,19 @@ func TestEngine(t *testing.T) {
 			So(engine.OpenAPI(), ShouldNotBeNil)
 		})
 
+		Convey("The OpenAPI document should reflect the registered routes", func() {
+			doc := engine.OpenAPI()
+			So(doc.Paths.Len(), ShouldEqual, 0)
+
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/users", handler).OK()
+			engine.Group("/admin").Delete("/users/:id", handler).OK()
+			So(engine.OpenAPI(), ShouldEqual, doc)
+			So(doc.Paths.Len(), ShouldEqual, 2)
+			So(doc.Paths.Find("/users").Get, ShouldNotBeNil)
+			So(doc.Paths.Find("/admin/users/:id").Delete, ShouldNotBeNil)
+		})
+
 		Convey("The App should not be nil", func() {
 			So(engine.App(), ShouldNotBeNil)
 		})
@@ -35,6 +58,7 @@ func TestEngine(t *testing.T) {
 		Convey("When serving the documentation UI", func() {
 			engine.ServeDocUI("/doc", &mockUIRender{})
 			engine.ServeDocUI("/elements", soda.UIStoplightElement)
+			engine.ServeDocUI("/scalar", soda.UIScalar)
 
 			Convey("The response should have status code 200", func() {
 				req := httptest.NewRequest("GET", "/doc", nil)
@@ -45,6 +69,123 @@ func TestEngine(t *testing.T) {
 				resp, _ = engine.App().Test(req)
 				So(resp.StatusCode, ShouldEqual, 200)
 			})
+
+			Convey("The Scalar UI should feed it the spec", func() {
+				req := httptest.NewRequest("GET", "/scalar", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				So(string(body), ShouldContainSubstring, `<script id="api-reference" type="application/json">{"components":{}`)
+				So(string(body), ShouldContainSubstring, "https://cdn.jsdelivr.net/npm/@scalar/api-reference@latest/dist/browser/standalone.js")
+			})
+		})
+
+		Convey("When serving UIs with pinned versions", func() {
+			engine.ServeReDocStandalone("/redoc", "v2.1.5")
+			engine.ServeDocUI("/swagger", soda.NewSwaggerUI(soda.WithUIVersion("5.17.14")))
+			engine.ServeDocUI("/rapidoc", soda.NewRapiDoc(soda.WithUIVersion("9.3.4")))
+			engine.ServeDocUI("/scalar", soda.NewScalar(soda.WithUIVersion("1.25.0")))
+
+			Convey("The HTML should reference the pinned versions", func() {
+				for url, expect := range map[string]string{
+					"/redoc":   "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js",
+					"/swagger": "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5.17.14/swagger-ui-bundle.js",
+					"/rapidoc": "https://cdn.jsdelivr.net/npm/rapidoc@9.3.4/dist/rapidoc-min.min.js",
+					"/scalar":  "https://cdn.jsdelivr.net/npm/@scalar/api-reference@1.25.0/dist/browser/standalone.js",
+				} {
+					req := httptest.NewRequest("GET", url, nil)
+					resp, _ := engine.App().Test(req)
+					So(resp.StatusCode, ShouldEqual, 200)
+					body, _ := io.ReadAll(resp.Body)
+					So(string(body), ShouldContainSubstring, expect)
+				}
+			})
+		})
+
+		Convey("When serving UIs with a custom CDN and theme", func() {
+			engine.ServeDocUI("/rapidoc", soda.NewRapiDoc(
+				soda.WithUICDN("https://mirror.example.com/rapidoc@{:version}"),
+				soda.WithUIVersion("9.3.4"),
+				soda.WithUITheme("light"),
+				soda.WithUIAttributes(map[string]string{"primary-color": "#000000", "render-style": "focused"}),
+			))
+			engine.ServeDocUI("/default", soda.UIRapiDoc)
+
+			get := func(path string) string {
+				resp, _ := engine.App().Test(httptest.NewRequest("GET", path, nil))
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				return string(body)
+			}
+
+			Convey("The HTML should use the custom values", func() {
+				body := get("/rapidoc")
+				So(body, ShouldContainSubstring, `src="https://mirror.example.com/rapidoc@9.3.4/rapidoc-min.min.js"`)
+				So(body, ShouldContainSubstring, `theme="light"`)
+				So(body, ShouldContainSubstring, `primary-color="#000000"`)
+				So(body, ShouldContainSubstring, `render-style="focused"`)
+				So(body, ShouldContainSubstring, `bg-color="#2e3746"`)
+				So(body, ShouldNotContainSubstring, `theme="dark"`)
+			})
+
+			Convey("The defaults should be left untouched", func() {
+				body := get("/default")
+				So(body, ShouldContainSubstring, `theme="dark"`)
+				So(body, ShouldContainSubstring, `primary-color="#f54c47"`)
+				So(body, ShouldNotContainSubstring, "render-style")
+			})
+		})
+
+		Convey("When serving a UI with embedded assets", func() {
+			assets := fstest.MapFS{
+				"swagger-ui.css":       {Data: []byte("body {}")},
+				"swagger-ui-bundle.js": {Data: []byte("function SwaggerUIBundle() {}")},
+			}
+			engine.ServeDocUIAssets("/offline", soda.NewSwaggerUI(soda.WithUIAssets(assets)))
+
+			Convey("The HTML should reference the local assets", func() {
+				req := httptest.NewRequest("GET", "/offline", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				So(string(body), ShouldContainSubstring, `src="/offline/assets/swagger-ui-bundle.js"`)
+				So(string(body), ShouldContainSubstring, `href="/offline/assets/swagger-ui.css"`)
+				So(string(body), ShouldNotContainSubstring, "cdn.jsdelivr.net")
+			})
+
+			Convey("The asset routes should respond 200", func() {
+				for _, name := range []string{"swagger-ui.css", "swagger-ui-bundle.js"} {
+					req := httptest.NewRequest("GET", "/offline/assets/"+name, nil)
+					resp, _ := engine.App().Test(req)
+					So(resp.StatusCode, ShouldEqual, 200)
+					body, _ := io.ReadAll(resp.Body)
+					So(body, ShouldResemble, assets[name].Data)
+				}
+				req := httptest.NewRequest("GET", "/offline/assets/missing.js", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 404)
+			})
+
+			Convey("ServeDocUI should register the asset routes of a UI with assets", func() {
+				rapidoc := fstest.MapFS{"rapidoc-min.min.js": {Data: []byte("customElements.define()")}}
+				engine.ServeDocUI("/rapidoc", soda.NewRapiDoc(soda.WithUIAssets(rapidoc)))
+
+				req := httptest.NewRequest("GET", "/rapidoc", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				So(string(body), ShouldContainSubstring, `src="/rapidoc/assets/rapidoc-min.min.js"`)
+
+				req = httptest.NewRequest("GET", "/rapidoc/assets/rapidoc-min.min.js", nil)
+				resp, _ = engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ = io.ReadAll(resp.Body)
+				So(body, ShouldNotBeEmpty)
+			})
+
+			Convey("A UI without assets should panic", func() {
+				So(func() { engine.ServeDocUIAssets("/nope", soda.UIRedoc) }, ShouldPanic)
+			})
 		})
 
 		Convey("When serving the specification JSON", func() {
@@ -57,6 +198,55 @@ func TestEngine(t *testing.T) {
 			})
 		})
 
+		Convey("When serving the protected specification JSON", func() {
+			engine.ServeSpecJSONProtected("/private/spec.json", func(c *fiber.Ctx) bool {
+				return c.Get("X-Token") == "secret"
+			})
+
+			Convey("An authorized request should get the spec", func() {
+				req := httptest.NewRequest("GET", "/private/spec.json", nil)
+				req.Header.Set("X-Token", "secret")
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				spec, _ := engine.OpenAPI().MarshalJSON()
+				So(body, ShouldResemble, spec)
+			})
+
+			Convey("An unauthorized request should be rejected", func() {
+				req := httptest.NewRequest("GET", "/private/spec.json", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 401)
+			})
+		})
+
+		Convey("When serving the specification JSONP", func() {
+			engine.ServeSpecJSONP("/spec.js", "callback")
+
+			Convey("The response should be wrapped in the callback", func() {
+				req := httptest.NewRequest("GET", "/spec.js?callback=app.load_spec", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				So(resp.Header.Get("Content-Type"), ShouldStartWith, "application/javascript")
+				body, _ := io.ReadAll(resp.Body)
+				spec, _ := engine.OpenAPI().MarshalJSON()
+				So(string(body), ShouldEqual, "/**/app.load_spec("+string(spec)+");")
+			})
+
+			Convey("The response should be plain JSON without a callback", func() {
+				req := httptest.NewRequest("GET", "/spec.js", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 200)
+				So(resp.Header.Get("Content-Type"), ShouldStartWith, "application/json")
+			})
+
+			Convey("An invalid callback should be rejected", func() {
+				req := httptest.NewRequest("GET", "/spec.js?callback=alert(1)//", nil)
+				resp, _ := engine.App().Test(req)
+				So(resp.StatusCode, ShouldEqual, 400)
+			})
+		})
+
 		Convey("When serving the specification YAML", func() {
 			engine.ServeSpecYAML("/spec.yaml")
 			req := httptest.NewRequest("GET", "/spec.yaml", nil)
@@ -67,6 +257,543 @@ func TestEngine(t *testing.T) {
 			})
 		})
 
+		Convey("When walking the operations", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/a", handler).OK()
+			engine.Post("/a", handler).OK()
+			engine.Get("/b", handler).OK()
+
+			var visited []string
+			engine.WalkOperations(func(method, path string, op *openapi3.Operation) {
+				visited = append(visited, method+" "+path)
+				op.AddParameter(openapi3.NewHeaderParameter("X-Request-ID"))
+			})
+
+			Convey("Every operation should be visited in order", func() {
+				So(visited, ShouldResemble, []string{"GET /a", "POST /a", "GET /b"})
+			})
+
+			Convey("Every operation should be mutated", func() {
+				engine.WalkOperations(func(method, path string, op *openapi3.Operation) {
+					So(op.Parameters.GetByInAndName("header", "X-Request-ID"), ShouldNotBeNil)
+				})
+			})
+		})
+
+		Convey("When removing operations", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/items/:id", handler).SetOperationID("get-item").OK()
+			engine.Delete("/items/:id", handler).OK()
+			engine.Get("/others", handler).OK()
+
+			engine.RemoveOperation("get", "/items/:id")
+
+			Convey("The operation should be removed from the spec", func() {
+				item := engine.OpenAPI().Paths.Find("/items/:id")
+				So(item.Get, ShouldBeNil)
+				So(item.Delete, ShouldNotBeNil)
+			})
+
+			Convey("The path should be removed with its last operation", func() {
+				engine.RemoveOperation("DELETE", "/items/:id")
+				So(engine.OpenAPI().Paths.Find("/items/:id"), ShouldBeNil)
+				So(engine.OpenAPI().Paths.Find("/others"), ShouldNotBeNil)
+			})
+
+			Convey("The operation ID should be released", func() {
+				engine.Get("/items/:id", handler).SetOperationID("get-item").OK()
+				So(engine.OpenAPI().Paths.Find("/items/:id").Get.OperationID, ShouldEqual, "get-item")
+			})
+
+			Convey("Unknown operations should be ignored", func() {
+				engine.RemoveOperation("PATCH", "/items/:id")
+				engine.RemoveOperation("GET", "/unknown")
+				So(engine.OpenAPI().Paths.Len(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When adding servers", func() {
+			engine.ServeSpecJSON("/openapi.json")
+			engine.ServeSpecYAML("/openapi.yaml")
+			engine.AddServer("https://api.example.com", "Production")
+			engine.AddServer("https://{env}.example.com:{port}", "Staging",
+				soda.ServerVariable{Name: "env", Default: "staging", Enum: []string{"staging", "qa"}},
+				soda.ServerVariable{Name: "port", Default: "443", Description: "The port"},
+			)
+
+			Convey("The servers should appear in the served JSON", func() {
+				req := httptest.NewRequest("GET", "/openapi.json", nil)
+				resp, _ := engine.App().Test(req)
+				var spec struct {
+					Servers []map[string]any `json:"servers"`
+				}
+				So(json.NewDecoder(resp.Body).Decode(&spec), ShouldBeNil)
+				So(spec.Servers, ShouldResemble, []map[string]any{
+					{"url": "https://api.example.com", "description": "Production"},
+					{
+						"url":         "https://{env}.example.com:{port}",
+						"description": "Staging",
+						"variables": map[string]any{
+							"env":  map[string]any{"default": "staging", "enum": []any{"staging", "qa"}},
+							"port": map[string]any{"default": "443", "description": "The port"},
+						},
+					},
+				})
+			})
+
+			Convey("The servers should appear in the served YAML", func() {
+				req := httptest.NewRequest("GET", "/openapi.yaml", nil)
+				resp, _ := engine.App().Test(req)
+				body, _ := io.ReadAll(resp.Body)
+				So(string(body), ShouldContainSubstring, "url: https://api.example.com")
+			})
+		})
+
+		Convey("When adding a server with variables bound from the environment", func() {
+			t.Setenv("API_REGION", "eu-west-1")
+			engine.AddServer("https://{region}.api.example.com", "Regional",
+				soda.ServerVariable{Name: "region", Default: "us-east-1", Env: "API_REGION"},
+				soda.ServerVariable{Name: "unset", Default: "fallback", Env: "API_UNSET_VARIABLE"},
+			)
+			engine.ServeSpecJSON("/openapi.json")
+
+			Convey("The variables should be resolved when the spec is built", func() {
+				req := httptest.NewRequest("GET", "/openapi.json", nil)
+				resp, _ := engine.App().Test(req)
+				var spec openapi3.T
+				So(json.NewDecoder(resp.Body).Decode(&spec), ShouldBeNil)
+				So(spec.Servers[0].Variables["region"].Default, ShouldEqual, "eu-west-1")
+				So(spec.Servers[0].Variables["unset"].Default, ShouldEqual, "fallback")
+			})
+		})
+
+		Convey("When adding routes after the spec was served", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/users", handler).OK()
+			engine.ServeSpecJSON("/openapi.json")
+			engine.ServeSpecYAML("/openapi.yaml")
+
+			get := func(path string) string {
+				resp, err := engine.App().Test(httptest.NewRequest("GET", path, nil))
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(resp.Body)
+				return string(body)
+			}
+			So(get("/openapi.json"), ShouldNotContainSubstring, "/admin")
+			So(get("/openapi.yaml"), ShouldNotContainSubstring, "/admin")
+
+			engine.Group("/admin").Get("/stats", handler).OK()
+
+			Convey("The served spec should contain the new paths", func() {
+				So(get("/openapi.json"), ShouldContainSubstring, `"/admin/stats"`)
+				So(get("/openapi.yaml"), ShouldContainSubstring, "/admin/stats:")
+			})
+
+			Convey("The removed paths should be dropped from the served spec", func() {
+				engine.RemoveOperation("GET", "/admin/stats")
+				So(get("/openapi.json"), ShouldNotContainSubstring, "/admin")
+			})
+
+			Convey("The direct changes should be served after a refresh", func() {
+				So(get("/openapi.json"), ShouldContainSubstring, `"/admin/stats"`)
+				engine.OpenAPI().Info.Title = "Refreshed"
+				So(get("/openapi.json"), ShouldNotContainSubstring, "Refreshed")
+				engine.RefreshSpec()
+				So(get("/openapi.json"), ShouldContainSubstring, `"title":"Refreshed"`)
+			})
+		})
+
+		Convey("When post-processing the built spec", func() {
+			var calls []string
+			engine.OnSpecBuilt(func(doc *openapi3.T) {
+				calls = append(calls, "first")
+				doc.Tags = append(doc.Tags, &openapi3.Tag{Name: "custom", Description: "Added by a hook"})
+			}).OnSpecBuilt(func(doc *openapi3.T) {
+				calls = append(calls, "second")
+				doc.Tags[len(doc.Tags)-1].Description += " twice"
+			})
+			engine.ServeSpecJSON("/openapi.json")
+			engine.ServeSpecYAML("/openapi.yaml")
+			So(calls, ShouldBeEmpty)
+
+			get := func(path string) string {
+				resp, err := engine.App().Test(httptest.NewRequest("GET", path, nil))
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(resp.Body)
+				return string(body)
+			}
+
+			Convey("The hooks should run once in registration order before the spec is served", func() {
+				So(get("/openapi.json"), ShouldContainSubstring, `"tags":[{"description":"Added by a hook twice","name":"custom"}]`)
+				So(get("/openapi.yaml"), ShouldContainSubstring, "description: Added by a hook twice")
+				So(calls, ShouldResemble, []string{"first", "second"})
+			})
+		})
+
+		Convey("When watching the spec", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/before", handler).OK()
+			engine.ServeSpecWatch("/watch")
+
+			get := func() *openapi3.T {
+				resp, err := engine.App().Test(httptest.NewRequest("GET", "/watch", nil))
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				doc, err := openapi3.NewLoader().LoadFromData(body)
+				So(err, ShouldBeNil)
+				return doc
+			}
+
+			Convey("The spec should reflect the routes added after it was served", func() {
+				So(get().Paths.Find("/before"), ShouldNotBeNil)
+				So(get().Paths.Find("/after"), ShouldBeNil)
+
+				engine.Get("/after", handler).OK()
+				So(get().Paths.Find("/after"), ShouldNotBeNil)
+			})
+
+			Convey("The events should carry the hash of the spec", func() {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				So(err, ShouldBeNil)
+				go func() { _ = engine.App().Listener(ln) }()
+				defer func() { _ = engine.App().ShutdownWithTimeout(time.Second) }()
+
+				resp, err := http.Get("http://" + ln.Addr().String() + "/watch/events")
+				So(err, ShouldBeNil)
+				defer resp.Body.Close()
+				So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+
+				reader := bufio.NewReader(resp.Body)
+				event, _ := reader.ReadString('\n')
+				data, _ := reader.ReadString('\n')
+				So(event, ShouldEqual, "event: spec\n")
+				So(data, ShouldStartWith, "data: ")
+				So(strings.TrimSpace(strings.TrimPrefix(data, "data: ")), ShouldHaveLength, 64)
+
+				Convey("And a heartbeat should be sent while the spec is unchanged", func() {
+					_, _ = reader.ReadString('\n')
+					ping, _ := reader.ReadString('\n')
+					So(ping, ShouldEqual, ": ping\n")
+				})
+			})
+		})
+
+		Convey("When serving the spec split by tag", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/users", handler).AddTags("users").OK()
+			engine.Post("/users", handler).AddTags("users").OK()
+			engine.Get("/orders", handler).AddTags("order items").OK()
+			engine.ServeSpecSplit("/specs")
+
+			get := func(path string) (int, []byte) {
+				resp, err := engine.App().Test(httptest.NewRequest("GET", path, nil))
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(resp.Body)
+				return resp.StatusCode, body
+			}
+
+			Convey("The index should list the sub-spec of each tag", func() {
+				code, body := get("/specs")
+				So(code, ShouldEqual, 200)
+				So(string(body), ShouldEqual, `{"specs":[{"tag":"order items","url":"/specs/order%20items.json"},{"tag":"users","url":"/specs/users.json"}]}`)
+			})
+
+			Convey("Each sub-spec should contain only the operations of its tag", func() {
+				code, body := get("/specs/users.json")
+				So(code, ShouldEqual, 200)
+				doc, err := openapi3.NewLoader().LoadFromData(body)
+				So(err, ShouldBeNil)
+				So(doc.Paths.Len(), ShouldEqual, 1)
+				So(doc.Paths.Find("/users").Operations(), ShouldHaveLength, 2)
+				So(doc.Tags, ShouldHaveLength, 1)
+
+				code, body = get("/specs/order%20items.json")
+				So(code, ShouldEqual, 200)
+				doc, err = openapi3.NewLoader().LoadFromData(body)
+				So(err, ShouldBeNil)
+				So(doc.Paths.Len(), ShouldEqual, 1)
+				So(doc.Paths.Find("/orders").Get, ShouldNotBeNil)
+			})
+
+			Convey("Unknown tags should not be found", func() {
+				code, _ := get("/specs/unknown.json")
+				So(code, ShouldEqual, 404)
+			})
+
+			Convey("Each sub-spec should be post-processed by the spec hooks", func() {
+				engine.OnSpecBuilt(func(doc *openapi3.T) {
+					doc.Info.Description = "post-processed"
+					doc.Paths.Find("/orders").Get.Tags = []string{"orders"}
+				})
+
+				code, body := get("/specs")
+				So(code, ShouldEqual, 200)
+				So(string(body), ShouldContainSubstring, `"url":"/specs/orders.json"`)
+
+				code, body = get("/specs/orders.json")
+				So(code, ShouldEqual, 200)
+				doc, err := openapi3.NewLoader().LoadFromData(body)
+				So(err, ShouldBeNil)
+				So(doc.Info.Description, ShouldEqual, "post-processed")
+				So(doc.Paths.Find("/orders").Get, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When serving the debug report of the schemas", func() {
+			type child struct {
+				Name string `json:"name"`
+			}
+			type parent struct {
+				Child    child   `json:"child"`
+				Children []child `json:"children"`
+			}
+			engine.Get("/parents", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, parent{}).OK()
+			engine.OpenAPI().Components.Schemas["Broken"] = openapi3.NewSchemaRef("#/components/schemas/Missing", nil)
+			engine.ServeDebugSchemas("/debug/schemas")
+
+			req := httptest.NewRequest("GET", "/debug/schemas", nil)
+			resp, _ := engine.App().Test(req)
+			So(resp.StatusCode, ShouldEqual, 200)
+			var report struct {
+				Schemas []map[string]any `json:"schemas"`
+			}
+			So(json.NewDecoder(resp.Body).Decode(&report), ShouldBeNil)
+
+			Convey("The report should list the components and their references", func() {
+				So(report.Schemas, ShouldHaveLength, 3)
+				So(report.Schemas[1], ShouldResemble, map[string]any{
+					"name": "soda_test.child",
+					"ref":  "#/components/schemas/soda_test.child",
+				})
+				So(report.Schemas[2], ShouldResemble, map[string]any{
+					"name": "soda_test.parent",
+					"ref":  "#/components/schemas/soda_test.parent",
+					"refs": []any{"#/components/schemas/soda_test.child"},
+				})
+			})
+
+			Convey("The report should flag the references failing to dereference", func() {
+				So(report.Schemas[0]["name"], ShouldEqual, "Broken")
+				So(report.Schemas[0]["error"], ShouldContainSubstring, "schema Missing not found")
+			})
+		})
+
+		Convey("When validating the spec", func() {
+			type input struct {
+				ID int `path:"id"`
+			}
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine := soda.New(soda.WithInfo("Pet Store", "1.0.0"))
+			engine.Get("/users/:id", handler).SetInput(input{}).AddJSONResponse(200, nil).OK()
+
+			Convey("A valid spec should pass", func() {
+				So(engine.Validate(), ShouldBeNil)
+			})
+
+			Convey("An invalid operation should be identified", func() {
+				engine.Put("/users/:id", handler).
+					SetInput(input{}).
+					AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewStringSchema())).
+					AddJSONResponse(200, nil).
+					OK()
+
+				err := engine.Validate()
+				So(err, ShouldNotBeNil)
+				var specErr *soda.SpecError
+				So(errors.As(err, &specErr), ShouldBeTrue)
+				So(specErr.Method, ShouldEqual, "PUT")
+				So(specErr.Path, ShouldEqual, "/users/:id")
+			})
+
+			Convey("An invalid document should be reported", func() {
+				engine := soda.New()
+				var specErr *soda.SpecError
+				So(errors.As(engine.Validate(), &specErr), ShouldBeTrue)
+				So(specErr.Path, ShouldBeEmpty)
+			})
+
+			Convey("The spec should be validated on serve with WithValidateOnServe", func() {
+				engine := soda.New(soda.WithValidateOnServe())
+				engine.ServeSpecJSON("/openapi.json")
+				engine.ServeSpecJSONP("/openapi.js", "callback")
+				engine.ServeSpecJSONProtected("/private.json", func(*fiber.Ctx) bool { return true })
+				get := func(path string) (int, string) {
+					resp, err := engine.App().Test(httptest.NewRequest("GET", path, nil))
+					So(err, ShouldBeNil)
+					body, _ := io.ReadAll(resp.Body)
+					return resp.StatusCode, string(body)
+				}
+
+				Convey("A default engine should be served", func() {
+					engine.Get("/users/:id", handler).SetInput(input{}).AddJSONResponse(200, nil).OK()
+					code, _ := get("/openapi.json")
+					So(code, ShouldEqual, 200)
+				})
+
+				Convey("The operations added after the spec was served should be validated", func() {
+					code, _ := get("/openapi.json")
+					So(code, ShouldEqual, 200)
+
+					engine.Put("/users/:id", handler).
+						SetInput(input{}).
+						AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewStringSchema())).
+						AddJSONResponse(200, nil).
+						OK()
+					for _, path := range []string{"/openapi.json", "/openapi.js?callback=cb", "/private.json"} {
+						code, body := get(path)
+						So(code, ShouldEqual, 500)
+						So(body, ShouldStartWith, "invalid operation PUT /users/:id")
+					}
+
+					engine.RemoveOperation("PUT", "/users/:id")
+					code, _ = get("/openapi.json")
+					So(code, ShouldEqual, 200)
+				})
+			})
+		})
+
+		Convey("When creating a new engine with the API information", func() {
+			engine := soda.New(
+				soda.WithInfo("Pet Store", "1.0.0"),
+				soda.WithDescription("Sells pets"),
+				soda.WithContact("API Support", "https://example.com/support", "support@example.com"),
+				soda.WithLicense("MIT", "https://opensource.org/licenses/MIT"),
+				soda.WithTermsOfService("https://example.com/terms"),
+			)
+			engine.ServeSpecJSON("/openapi.json")
+
+			Convey("The info block should be served in the spec", func() {
+				req := httptest.NewRequest("GET", "/openapi.json", nil)
+				resp, _ := engine.App().Test(req)
+				var spec struct {
+					Info map[string]any `json:"info"`
+				}
+				So(json.NewDecoder(resp.Body).Decode(&spec), ShouldBeNil)
+				So(spec.Info, ShouldResemble, map[string]any{
+					"title":          "Pet Store",
+					"version":        "1.0.0",
+					"description":    "Sells pets",
+					"termsOfService": "https://example.com/terms",
+					"contact": map[string]any{
+						"name":  "API Support",
+						"url":   "https://example.com/support",
+						"email": "support@example.com",
+					},
+					"license": map[string]any{"name": "MIT", "url": "https://opensource.org/licenses/MIT"},
+				})
+			})
+		})
+
+		Convey("When merging the specs of engines with component prefixes", func() {
+			type user struct {
+				Name string `json:"name"`
+			}
+			merged := soda.New().OpenAPI()
+			for _, prefix := range []string{"admin.", "public."} {
+				engine := soda.New(soda.WithInfo("Users", "1.0.0"), soda.WithComponentPrefix(prefix))
+				engine.Get("/users", func(c *fiber.Ctx) error { return nil }).
+					SetOperationID(prefix+"listUsers").
+					AddJSONResponse(200, []user{}).
+					OK()
+				for name, schema := range engine.OpenAPI().Components.Schemas {
+					So(merged.Components.Schemas, ShouldNotContainKey, name)
+					merged.Components.Schemas[name] = schema
+				}
+				item := engine.OpenAPI().Paths.Find("/users")
+				merged.Paths.Set("/"+strings.TrimSuffix(prefix, ".")+"/users", item)
+			}
+
+			Convey("The components should be prefixed", func() {
+				So(merged.Components.Schemas, ShouldContainKey, "admin.soda_test.user")
+				So(merged.Components.Schemas, ShouldContainKey, "public.soda_test.user")
+			})
+
+			Convey("The refs should point to the prefixed components", func() {
+				schema := merged.Paths.Find("/admin/users").Get.Responses.Status(200).Value.Content["application/json"].Schema
+				So(schema.Value.Items.Ref, ShouldEqual, "#/components/schemas/admin.soda_test.user")
+				merged.Info = &openapi3.Info{Title: "Merged", Version: "1.0.0"}
+				So(merged.Validate(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When setting a default error response", func() {
+			type ErrorResponse struct {
+				Message string `json:"message"`
+			}
+			type ValidationErrors struct {
+				Fields []string `json:"fields"`
+			}
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine := soda.New(soda.WithInfo("Shop", "1.0.0"))
+			engine.Get("/before", handler).OK()
+			engine.SetDefaultErrorResponse(400, ErrorResponse{}, "Invalid request")
+			engine.Get("/after", handler).AddJSONResponse(200, nil).OK()
+			engine.Post("/override", handler).AddJSONResponse(400, ValidationErrors{}).OK()
+			engine.Group("/admin").AddJSONResponse(400, ValidationErrors{}).Get("/grouped", handler).OK()
+			responses := func(path, method string) *openapi3.Responses {
+				return engine.OpenAPI().Paths.Find(path).GetOperation(method).Responses
+			}
+
+			Convey("The response should be registered once in the components", func() {
+				So(engine.OpenAPI().Components.Responses, ShouldHaveLength, 1)
+				response := engine.OpenAPI().Components.Responses["BadRequest"].Value
+				So(*response.Description, ShouldEqual, "Invalid request")
+				So(response.Content["application/json"].Schema.Ref, ShouldEqual, "#/components/schemas/soda_test.ErrorResponse")
+			})
+
+			Convey("Every operation without a 400 response should reference it", func() {
+				So(responses("/before", "GET").Status(400).Ref, ShouldEqual, "#/components/responses/BadRequest")
+				So(responses("/after", "GET").Status(400).Ref, ShouldEqual, "#/components/responses/BadRequest")
+				So(responses("/after", "GET").Status(200), ShouldNotBeNil)
+				So(engine.Validate(), ShouldBeNil)
+			})
+
+			Convey("The explicit responses should be kept", func() {
+				override := responses("/override", "POST").Status(400)
+				So(override.Ref, ShouldBeEmpty)
+				So(override.Value.Content["application/json"].Schema.Ref, ShouldEqual, "#/components/schemas/soda_test.ValidationErrors")
+				So(responses("/admin/grouped", "GET").Status(400).Ref, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When serving the spec in both formats from one endpoint", func() {
+			engine := soda.New(soda.WithInfo("Pet Store", "1.0.0"))
+			engine.ServeSpec("/openapi")
+			serve := func(path, accept string) (string, string) {
+				req := httptest.NewRequest("GET", path, nil)
+				if accept != "" {
+					req.Header.Set("Accept", accept)
+				}
+				resp, err := engine.App().Test(req)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(resp.Body)
+				return resp.Header.Get("Content-Type"), string(body)
+			}
+
+			Convey("The format should follow the suffix of the path", func() {
+				contentType, body := serve("/openapi.json", "text/yaml")
+				So(contentType, ShouldStartWith, "application/json")
+				So(body, ShouldStartWith, "{")
+
+				contentType, body = serve("/openapi.yaml", "")
+				So(contentType, ShouldStartWith, "text/yaml")
+				So(body, ShouldContainSubstring, "openapi: 3.0.3")
+			})
+
+			Convey("The format should follow the Accept header without a suffix", func() {
+				contentType, _ := serve("/openapi", "application/yaml")
+				So(contentType, ShouldStartWith, "text/yaml")
+
+				contentType, _ = serve("/openapi", "application/json")
+				So(contentType, ShouldStartWith, "application/json")
+
+				contentType, _ = serve("/openapi", "")
+				So(contentType, ShouldStartWith, "application/json")
+			})
+		})
+
 		Convey("When creating a new engine with a custom fiber App", func() {
 			app := fiber.New()
 			newEngine := soda.NewWith(app)
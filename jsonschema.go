@@ -0,0 +1,144 @@
+package soda
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const jsonSchemaDraft202012 = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateJSONSchema generates a standalone JSON Schema (draft 2020-12) document for a given model.
+// The schemas that would be OpenAPI components are emitted under `$defs`.
+func GenerateJSONSchema(model any) ([]byte, error) {
+	g := NewGenerator()
+	ref, err := g.GenerateSchemaRefE(model, "json")
+	if err != nil {
+		return nil, err
+	}
+
+	document := make(map[string]any)
+	if ref.Ref != "" {
+		document["$ref"] = ref.Ref
+	} else if err := remarshal(ref.Value, &document); err != nil {
+		return nil, err
+	}
+	return marshalJSONSchema(g, document)
+}
+
+// generateJSONSchemaDefs generates a JSON Schema (draft 2020-12) document defining the given models under `$defs`,
+// with the schema options of the given generator. The models must be named types, so that they are defined rather than inlined.
+func generateJSONSchemaDefs(options *Generator, models []any) ([]byte, error) {
+	g := options.cloneOptions()
+	for _, model := range models {
+		ref, err := g.GenerateSchemaRefE(model, "json")
+		if err != nil {
+			return nil, err
+		}
+		if ref.Ref == "" {
+			return nil, fmt.Errorf("model %T is not a named type", model)
+		}
+	}
+	return marshalJSONSchema(g, make(map[string]any))
+}
+
+// marshalJSONSchema adds the component schemas of the generator to the document as `$defs`,
+// and marshals it as a JSON Schema document.
+func marshalJSONSchema(g *Generator, document map[string]any) ([]byte, error) {
+	defs := make(map[string]any, len(g.doc.Components.Schemas))
+	for name, schema := range g.doc.Components.Schemas {
+		var def map[string]any
+		if err := remarshal(schema.Value, &def); err != nil {
+			return nil, err
+		}
+		defs[name] = def
+	}
+	if len(defs) != 0 {
+		document["$defs"] = defs
+	}
+	document["$schema"] = jsonSchemaDraft202012
+
+	return json.Marshal(toJSONSchema(document))
+}
+
+// remarshal converts a schema into its generic JSON representation.
+func remarshal(schema *openapi3.Schema, out any) error {
+	data, err := schema.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// toJSONSchema converts the OpenAPI 3.0 specific keywords of a generic schema into their JSON Schema equivalent.
+func toJSONSchema(schema map[string]any) map[string]any {
+	// convert the nested schemas
+	for _, keyword := range []string{"properties", "$defs"} {
+		if schemas, ok := schema[keyword].(map[string]any); ok {
+			for name, sub := range schemas {
+				if sub, ok := sub.(map[string]any); ok {
+					schemas[name] = toJSONSchema(sub)
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := schema[keyword].(map[string]any); ok {
+			schema[keyword] = toJSONSchema(sub)
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := schema[keyword].([]any); ok {
+			for i, sub := range subs {
+				if sub, ok := sub.(map[string]any); ok {
+					subs[i] = toJSONSchema(sub)
+				}
+			}
+		}
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		schema["$ref"] = strings.Replace(ref, "#/components/schemas/", "#/$defs/", 1)
+	}
+	for exclusive, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+		if b, ok := schema[exclusive].(bool); ok {
+			delete(schema, exclusive)
+			if b {
+				schema[exclusive] = schema[bound]
+				delete(schema, bound)
+			}
+		}
+	}
+	if propertyNames, ok := schema[extPropertyNames]; ok {
+		schema["propertyNames"] = propertyNames
+		delete(schema, extPropertyNames)
+	}
+	if example, ok := schema["example"]; ok {
+		schema["examples"] = []any{example}
+		delete(schema, "example")
+	}
+	if nullable, ok := schema["nullable"].(bool); ok {
+		delete(schema, "nullable")
+		if nullable {
+			return nullableJSONSchema(schema)
+		}
+	}
+	return schema
+}
+
+// nullableJSONSchema allows null for a schema which was nullable.
+// A typed schema gets null among its types and its enum, any other schema is wrapped in an anyOf with the null type.
+func nullableJSONSchema(schema map[string]any) map[string]any {
+	typ, ok := schema["type"].(string)
+	if !ok {
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+	schema["type"] = []any{typ, "null"}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(v any) bool { return v == nil }) {
+		schema["enum"] = append(enum, nil)
+	}
+	return schema
+}
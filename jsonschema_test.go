@@ -0,0 +1,171 @@
+package soda_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo-f/soda/v3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGenerateJSONSchema(t *testing.T) {
+	Convey("Given a struct model", t, func() {
+		type Address struct {
+			City string `json:"city" oai:"minLength=1"`
+		}
+		type Config struct {
+			Name     string            `json:"name"    oai:"example=soda"`
+			Port     int               `json:"port"    oai:"minimum=1;exclusiveMinimum"`
+			Nickname *string           `json:"nickname" oai:"nullable"`
+			Address  Address           `json:"address"`
+			Backups  []Config          `json:"backups"`
+			Labels   map[string]string `json:"labels" oai:"propertyNamesPattern=^[a-z]+$"`
+		}
+
+		data, err := soda.GenerateJSONSchema(Config{})
+		So(err, ShouldBeNil)
+
+		var document map[string]any
+		So(json.Unmarshal(data, &document), ShouldBeNil)
+
+		Convey("It should be a draft 2020-12 document referencing its $defs", func() {
+			So(document["$schema"], ShouldEqual, "https://json-schema.org/draft/2020-12/schema")
+			So(document["$ref"], ShouldEqual, "#/$defs/soda_test.Config")
+			So(document["$defs"], ShouldContainKey, "soda_test.Config")
+			So(document["$defs"], ShouldContainKey, "soda_test.Address")
+		})
+
+		Convey("It should convert the OpenAPI specific keywords", func() {
+			config := document["$defs"].(map[string]any)["soda_test.Config"].(map[string]any)
+			properties := config["properties"].(map[string]any)
+			So(properties["address"], ShouldResemble, map[string]any{"$ref": "#/$defs/soda_test.Address"})
+			So(properties["backups"].(map[string]any)["items"], ShouldResemble, map[string]any{"$ref": "#/$defs/soda_test.Config"})
+			So(properties["nickname"].(map[string]any)["type"], ShouldResemble, []any{"string", "null"})
+			So(properties["port"].(map[string]any)["exclusiveMinimum"], ShouldEqual, 1)
+			So(properties["port"].(map[string]any), ShouldNotContainKey, "minimum")
+			So(properties["name"].(map[string]any)["examples"], ShouldResemble, []any{"soda"})
+			So(properties["labels"].(map[string]any)["propertyNames"], ShouldResemble, map[string]any{"type": "string", "pattern": "^[a-z]+$"})
+		})
+
+		Convey("It should validate an instance", func() {
+			address, _ := json.Marshal(document["$defs"].(map[string]any)["soda_test.Address"])
+			schema := openapi3.NewSchema()
+			So(schema.UnmarshalJSON(address), ShouldBeNil)
+			So(schema.VisitJSON(map[string]any{"city": "Paris"}), ShouldBeNil)
+			So(schema.VisitJSON(map[string]any{"city": ""}), ShouldNotBeNil)
+			So(schema.VisitJSON(map[string]any{}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a model with nullable fields which are not plain types", t, func() {
+		type Address struct {
+			City string `json:"city"`
+		}
+		type Contact struct {
+			Address *Address `json:"address" oai:"nullable"`
+			Status  *string  `json:"status"  oai:"nullable;enum=active,inactive"`
+		}
+		engine := soda.New(soda.WithPointersNullable()).ServeJSONSchema("/schemas.json", Contact{})
+		response, err := engine.App().Test(httptest.NewRequest("GET", "/schemas.json", nil))
+		So(err, ShouldBeNil)
+		var document map[string]any
+		So(json.NewDecoder(response.Body).Decode(&document), ShouldBeNil)
+		properties := document["$defs"].(map[string]any)["soda_test.Contact"].(map[string]any)["properties"].(map[string]any)
+
+		Convey("It should allow null besides a nullable reference", func() {
+			So(properties["address"], ShouldResemble, map[string]any{
+				"anyOf": []any{
+					map[string]any{"allOf": []any{map[string]any{"$ref": "#/$defs/soda_test.Address"}}},
+					map[string]any{"type": "null"},
+				},
+			})
+		})
+
+		Convey("It should allow null among the values of a nullable enum", func() {
+			So(properties["status"].(map[string]any)["type"], ShouldResemble, []any{"string", "null"})
+			So(properties["status"].(map[string]any)["enum"], ShouldResemble, []any{"active", "inactive", nil})
+		})
+	})
+
+	Convey("Given a primitive model", t, func() {
+		data, err := soda.GenerateJSONSchema("")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"string"}`)
+	})
+
+	Convey("Given an unsupported model", t, func() {
+		_, err := soda.GenerateJSONSchema(make(chan int))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given an engine serving the JSON Schema of models", t, func() {
+		type Line struct {
+			Product  string `json:"product"`
+			Quantity int    `json:"quantity" oai:"minimum=1"`
+		}
+		type Order struct {
+			ID    string `json:"id"`
+			Lines []Line `json:"lines"`
+		}
+		type Customer struct {
+			Name string `json:"name"`
+		}
+		engine := soda.New().ServeJSONSchema("/schemas.json", Order{}, Customer{})
+
+		request := httptest.NewRequest("GET", "/schemas.json", nil)
+		response, err := engine.App().Test(request)
+		So(err, ShouldBeNil)
+		So(response.Header.Get("Content-Type"), ShouldStartWith, "application/schema+json")
+		var document map[string]any
+		So(json.NewDecoder(response.Body).Decode(&document), ShouldBeNil)
+
+		Convey("It should define the models and their nested types under $defs", func() {
+			So(document["$schema"], ShouldEqual, "https://json-schema.org/draft/2020-12/schema")
+			So(document, ShouldNotContainKey, "$ref")
+			defs := document["$defs"].(map[string]any)
+			So(defs, ShouldHaveLength, 3)
+			So(defs, ShouldContainKey, "soda_test.Customer")
+			So(defs, ShouldContainKey, "soda_test.Line")
+			order := defs["soda_test.Order"].(map[string]any)
+			lines := order["properties"].(map[string]any)["lines"].(map[string]any)
+			So(lines["items"], ShouldResemble, map[string]any{"$ref": "#/$defs/soda_test.Line"})
+		})
+
+		Convey("It should panic for models which are not named types", func() {
+			So(func() { soda.New().ServeJSONSchema("/schemas.json", "") }, ShouldPanic)
+		})
+
+		Convey("It should name the models as in the spec of the engine", func() {
+			engine := soda.New(soda.WithComponentPrefix("shop.")).
+				RegisterTypeName(reflect.TypeOf(Customer{}), "Client").
+				ServeJSONSchema("/schemas.json", Order{}, Customer{})
+			engine.Get("/orders", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(200, Order{}).
+				AddJSONResponse(201, Customer{}).
+				OK()
+
+			response, err := engine.App().Test(httptest.NewRequest("GET", "/schemas.json", nil))
+			So(err, ShouldBeNil)
+			var document map[string]any
+			So(json.NewDecoder(response.Body).Decode(&document), ShouldBeNil)
+			var names []string
+			for name := range document["$defs"].(map[string]any) {
+				names = append(names, name)
+			}
+			var components []string
+			for name := range engine.OpenAPI().Components.Schemas {
+				components = append(components, name)
+			}
+			So(names, ShouldContain, "shop.Client")
+			So(names, ShouldContain, "shop.soda_test.Order")
+			So(names, ShouldHaveLength, len(components))
+			for _, name := range components {
+				So(names, ShouldContain, name)
+			}
+		})
+	})
+}
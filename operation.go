@@ -1,11 +1,16 @@
 package soda
 
 import (
+	"database/sql"
+	"encoding/json"
+	"maps"
 	"net/http"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gofiber/fiber/v2"
@@ -33,10 +38,29 @@ type OperationBuilder struct {
 	inputBody          reflect.Type
 	inputBodyField     string
 	inputBodyMediaType string
+	inputBodyContent   []string
+	inputBodyOptional  bool
+	// inputBodyRaw is set for the []byte bodies with `oai:"format=binary"`, bound with the raw request body.
+	inputBodyRaw   bool
+	requestBodyRef *openapi3.RequestBodyRef
+
+	manualParameters openapi3.Parameters
+	parameterChecks  []parameterCheck
+	requiredParams   []requiredParameter
+	contentParams    []contentParameter
+	arrayParams      []arrayParameter
+	flagParams       []flagParameter
+	pathStyles       map[string]pathStyle
+	bearerFields     [][]int
+	headerJoins      map[string]string
 
 	handlers []fiber.Handler
 
-	ignoreAPIDoc bool
+	ignoreAPIDoc           bool
+	validateResponses      bool
+	idempotencyKeyRequired bool
+	successCode            int
+	sunset                 time.Time
 
 	// hooks
 	hooksBeforeBind []HookBeforeBind
@@ -81,6 +105,32 @@ func (op *OperationBuilder) SetDeprecated(deprecated bool) *OperationBuilder {
 	return op
 }
 
+// SetDeprecatedWithSunset marks the operation as deprecated, to be removed at the sunset date.
+// The responses of the operation carry the Deprecation and Sunset headers, which are documented on every response.
+func (op *OperationBuilder) SetDeprecatedWithSunset(sunset time.Time) *OperationBuilder {
+	op.operation.Deprecated = true
+	op.sunset = sunset
+	return op
+}
+
+// AddExtension sets a vendor extension of the operation, e.g. x-internal, the key must start with "x-".
+func (op *OperationBuilder) AddExtension(key string, value any) *OperationBuilder {
+	if !strings.HasPrefix(key, extPrefix) {
+		panic("extension " + key + " must start with " + extPrefix)
+	}
+	if op.operation.Extensions == nil {
+		op.operation.Extensions = make(map[string]any)
+	}
+	op.operation.Extensions[key] = value
+	return op
+}
+
+// SetDisplayOrder sets the position of the operation in the documentation, emitted as the x-displayOrder extension.
+// The UIs list the operations by ascending order, and the tags by the lowest order of their operations.
+func (op *OperationBuilder) SetDisplayOrder(order int) *OperationBuilder {
+	return op.AddExtension(extDisplayOrder, order)
+}
+
 // SetInput sets the input type for the operation.
 func (op *OperationBuilder) SetInput(input any) *OperationBuilder {
 	inputType := reflect.TypeOf(input)
@@ -95,18 +145,126 @@ func (op *OperationBuilder) SetInput(input any) *OperationBuilder {
 	op.input = inputType
 	op.setInputBody(inputType)
 
-	op.operation.Parameters = op.route.gen.GenerateParameters(inputType)
+	parameters := op.route.gen.GenerateParameters(inputType)
+	op.parameterChecks = newParameterChecks(inputType, parameters, op.route.gen.validateFormats)
+	if op.route.gen.requireParameters {
+		op.requiredParams = newRequiredParameters(parameters)
+	}
+	op.contentParams = newContentParameters(inputType, parameters)
+	op.arrayParams = newArrayParameters(inputType, parameters)
+	op.flagParams = newFlagParameters(inputType, parameters)
+	op.pathStyles = newPathStyles(parameters)
+	op.operation.Parameters = append(parameters, op.manualParameters...)
 	op.setRequestBody()
+
+	op.headerJoins = make(map[string]string)
+	collectHeaderJoins(inputType, op.headerJoins)
+
+	// the bearer tokens are documented as a security requirement rather than a header parameter
+	op.bearerFields = collectBearerFields(inputType, nil)
+	if len(op.bearerFields) > 0 {
+		if _, ok := op.route.gen.doc.Components.SecuritySchemes[BearerSecurityName]; !ok {
+			op.route.gen.doc.Components.SecuritySchemes[BearerSecurityName] = &openapi3.SecuritySchemeRef{
+				Value: NewBearerSecurityScheme(),
+			}
+		}
+		op.addSecurityRequirement(BearerSecurityName)
+	}
+	return op
+}
+
+// collectHeaderJoins collects the separators of the string header fields tagged with `oai:"joinWith=,"`,
+// keyed by the lower-cased header name.
+func collectHeaderJoins(t reflect.Type, joins map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			collectHeaderJoins(f.Type, joins)
+			continue
+		}
+		field := newTagsResolver(f)
+		sep, ok := field.pairs[propJoinWith]
+		if !ok || f.Tag.Get(HeaderTag) == "" || f.Type.Kind() != reflect.String {
+			continue
+		}
+		joins[strings.ToLower(field.name(HeaderTag))] = sep
+	}
+}
+
+// collectBearerFields returns the indexes of the string fields tagged with `oai:"from=bearer"`.
+func collectBearerFields(t reflect.Type, index []int) [][]int {
+	var fields [][]int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(slices.Clone(index), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectBearerFields(f.Type, fieldIndex)...)
+			continue
+		}
+		if newTagsResolver(f).from() != fromBearer {
+			continue
+		}
+		if f.Type.Kind() != reflect.String {
+			panic("bearer token field " + f.Name + " must be a string")
+		}
+		fields = append(fields, fieldIndex)
+	}
+	return fields
+}
+
+// AddParameter adds a manually specified parameter to the operation.
+// It is only documented, the parameter is not bound to the input.
+func (op *OperationBuilder) AddParameter(parameter *openapi3.Parameter) *OperationBuilder {
+	ref := &openapi3.ParameterRef{Value: parameter}
+	op.manualParameters = append(op.manualParameters, ref)
+	op.operation.Parameters = append(op.operation.Parameters, ref)
 	return op
 }
 
+// AddRequestHeaderParam documents a string header parameter of the request, for the one-off headers not worth an input field.
+// Like AddParameter, the header is not bound to the input.
+func (op *OperationBuilder) AddRequestHeaderParam(name string, required bool, description string) *OperationBuilder {
+	parameter := openapi3.NewHeaderParameter(name).
+		WithDescription(description).
+		WithRequired(required).
+		WithSchema(openapi3.NewStringSchema())
+	return op.AddParameter(parameter)
+}
+
 // setInputBody sets the input body from the input type.
 func (op *OperationBuilder) setInputBody(inputType reflect.Type) {
 	for i := 0; i < inputType.NumField(); i++ {
 		if body := inputType.Field(i); body.Tag.Get("body") != "" {
 			op.inputBody = body.Type
-			op.inputBodyMediaType = body.Tag.Get("body")
 			op.inputBodyField = body.Name
+			op.inputBodyRaw = body.Type == wnByteSlice && newTagsResolver(body).pairs[propFormat] == formatBinary
+			// the pointer bodies are left nil when no body is sent
+			op.inputBodyOptional = body.Type.Kind() == reflect.Ptr
+			var aliases []string
+			for _, alias := range strings.Split(body.Tag.Get("body"), ",") {
+				if strings.TrimSpace(alias) == bodyOptional {
+					op.inputBodyOptional = true
+					continue
+				}
+				aliases = append(aliases, alias)
+			}
+			op.inputBodyContent = nil
+			for i, alias := range aliases {
+				mediaType, ok := resolveMediaType(strings.TrimSpace(alias))
+				if !ok {
+					mediaType = fiber.MIMEApplicationJSON
+				}
+				// the first media type names the properties of the body schema
+				if i == 0 {
+					op.inputBodyMediaType = alias
+					if ok {
+						op.inputBodyMediaType = nameTagOf(mediaType)
+					}
+				}
+				if !slices.Contains(op.inputBodyContent, mediaType) {
+					op.inputBodyContent = append(op.inputBodyContent, mediaType)
+				}
+			}
 			break
 		}
 	}
@@ -114,44 +272,231 @@ func (op *OperationBuilder) setInputBody(inputType reflect.Type) {
 
 // setRequestBody sets the request body.
 func (op *OperationBuilder) setRequestBody() {
-	if op.inputBodyField == "" {
+	if op.inputBodyField == "" || op.requestBodyRef != nil {
 		return
 	}
-	op.operation.RequestBody = &openapi3.RequestBodyRef{
-		Value: op.route.gen.GenerateRequestBody(
-			op.operation.OperationID,
-			op.inputBodyMediaType,
-			op.inputBody,
-		),
+	requestBody := op.route.gen.GenerateRequestBody(
+		op.operation.OperationID,
+		op.inputBodyMediaType,
+		op.inputBody,
+	)
+	mediaType := requestBody.Content.Get(fiber.MIMEApplicationJSON)
+	content := op.inputBodyContent
+	// raw bytes bodies honor the OAI tags of the body field, e.g. `oai:"format=binary"`
+	if op.inputBody == wnByteSlice {
+		field, _ := op.input.FieldByName(op.inputBodyField)
+		newTagsResolver(field).injectOAITags(mediaType.Schema.Value)
+		if mediaType.Schema.Value.Format == formatBinary {
+			content = []string{fiber.MIMEOctetStream}
+		}
+	}
+	// the same schema is documented for every accepted media type
+	if !slices.Equal(content, []string{fiber.MIMEApplicationJSON}) {
+		requestBody.Content = openapi3.NewContent()
+		for _, mt := range content {
+			requestBody.Content[mt] = &openapi3.MediaType{
+				Schema:   mediaType.Schema,
+				Example:  mediaType.Example,
+				Examples: mediaType.Examples,
+			}
+		}
+	}
+	if op.inputBodyOptional {
+		requestBody.Required = false
 	}
+	op.operation.RequestBody = &openapi3.RequestBodyRef{Value: requestBody}
 }
 
-// AddSecurity adds a security scheme to the operation.
-func (op *OperationBuilder) AddSecurity(securityName string, scheme *openapi3.SecurityScheme) *OperationBuilder {
+// SetRequestBodyRef references a request body registered with Engine.DefineRequestBody as the request body of the operation.
+// It takes precedence over the request body generated from the input.
+func (op *OperationBuilder) SetRequestBodyRef(name string) *OperationBuilder {
+	requestBody, ok := op.route.gen.doc.Components.RequestBodies[name]
+	if !ok {
+		panic("request body " + name + " is not defined")
+	}
+	op.requestBodyRef = &openapi3.RequestBodyRef{
+		Ref:   "#/components/requestBodies/" + name,
+		Value: requestBody.Value,
+	}
+	op.operation.RequestBody = op.requestBodyRef
+	return op
+}
+
+// AddResponseRef references a response registered with Engine.RegisterResponse as the response for the status code.
+func (op *OperationBuilder) AddResponseRef(code int, name string) *OperationBuilder {
+	ref := op.route.gen.responseRef(name)
+	if ref == nil {
+		panic("response " + name + " is not defined")
+	}
+	if op.operation.Responses == nil {
+		op.operation.Responses = openapi3.NewResponses()
+	}
+	op.operation.Responses.Set(strconv.Itoa(code), ref)
+	return op
+}
+
+// AddSecurity adds a security scheme to the operation, optionally requiring the given scopes.
+func (op *OperationBuilder) AddSecurity(securityName string, scheme *openapi3.SecurityScheme, scopes ...string) *OperationBuilder {
 	op.route.gen.doc.Components.SecuritySchemes[securityName] = &openapi3.SecuritySchemeRef{
 		Value: scheme,
 	}
-	op.operation.Security.With(openapi3.NewSecurityRequirement().Authenticate(securityName))
+	op.addSecurityRequirement(securityName, scopes...)
 	return op
 }
 
+// addSecurityRequirement adds a security requirement to the operation,
+// without altering the requirements shared with the router.
+func (op *OperationBuilder) addSecurityRequirement(securityName string, scopes ...string) {
+	security := slices.Clone(*op.operation.Security)
+	op.operation.Security = security.With(openapi3.NewSecurityRequirement().Authenticate(securityName, scopes...))
+}
+
 // AddJSONResponse adds a JSON response to the operation.
+// The list endpoints may pass the model as a slice, a pointer to a slice or a slice of pointers, e.g. &[]User{},
+// they are all documented as an array of the referenced item schema.
 func (op *OperationBuilder) AddJSONResponse(code int, model any, description ...string) *OperationBuilder {
 	desc := http.StatusText(code)
 	if len(description) > 0 {
 		desc = description[0]
 	}
 	ref := op.route.gen.GenerateResponse(code, model, "application/json", desc)
-	op.operation.AddResponse(code, ref)
+	op.addResponse(code, ref)
 	return op
 }
 
+// AddJSONResponseNamed adds a JSON response to the operation, registering its schema component under the given name.
+func (op *OperationBuilder) AddJSONResponseNamed(code int, name string, model any, description ...string) *OperationBuilder {
+	desc := http.StatusText(code)
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	ref := op.route.gen.generateResponse(code, model, "application/json", desc, name)
+	op.addResponse(code, ref)
+	return op
+}
+
+// AddProblemResponse adds an RFC 7807 application/problem+json error response to the operation,
+// documented with the Problem component schema.
+func (op *OperationBuilder) AddProblemResponse(code int, description ...string) *OperationBuilder {
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	op.addResponse(code, op.route.gen.generateProblemResponse(code, desc))
+	return op
+}
+
+// addResponse adds a response to the operation, merging its content into the response already declared for the code.
+// The declared response is copied, as it may be shared with the other operations of the router.
+func (op *OperationBuilder) addResponse(code int, response *openapi3.Response) {
+	existing := op.operation.Responses.Status(code)
+	if existing == nil || existing.Value == nil || len(existing.Value.Content) == 0 {
+		op.operation.AddResponse(code, response)
+		return
+	}
+	merged := *existing.Value
+	merged.Content = maps.Clone(existing.Value.Content)
+	maps.Copy(merged.Content, response.Content)
+	op.operation.AddResponse(code, &merged)
+}
+
+// AddResponse adds a response with the given media type to the operation.
+// Responses of different media types declared for the same code are merged.
+func (op *OperationBuilder) AddResponse(code int, model any, mediaType string, description ...string) *OperationBuilder {
+	desc := http.StatusText(code)
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	ref := op.route.gen.GenerateResponse(code, model, mediaType, desc)
+	op.addResponse(code, ref)
+	return op
+}
+
+// SetIdempotencyKeyParam declares the Idempotency-Key header parameter of the operation.
+// When required, the requests without the header are rejected with a ValidationError.
+func (op *OperationBuilder) SetIdempotencyKeyParam(required bool) *OperationBuilder {
+	op.idempotencyKeyRequired = required
+	parameter := openapi3.NewHeaderParameter(HeaderIdempotencyKey).
+		WithDescription("A unique key identifying the request, so that it can be safely retried").
+		WithRequired(required).
+		WithSchema(openapi3.NewStringSchema())
+	return op.AddParameter(parameter)
+}
+
+// AddResponseHeader documents a header of the response with the given status code, e.g. X-Rate-Limit or Location.
+// The schema of the header is generated from the given model, and the response is created if it is not declared yet.
+func (op *OperationBuilder) AddResponseHeader(code int, name string, model any, description string) *OperationBuilder {
+	response := op.operation.Responses.Status(code)
+	if response == nil || response.Value == nil {
+		op.operation.AddResponse(code, openapi3.NewResponse().WithDescription(http.StatusText(code)))
+		response = op.operation.Responses.Status(code)
+	}
+	if response.Value.Headers == nil {
+		response.Value.Headers = openapi3.Headers{}
+	}
+	schema := op.route.gen.generateSchemaRef(nil, reflect.TypeOf(model), "json")
+	response.Value.Headers[name] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+		Description: description,
+		Schema:      schema,
+	}}}
+	return op
+}
+
+// SetResponseDescriptions sets the descriptions of the responses by status code.
+// The responses not documented yet are added without content.
+func (op *OperationBuilder) SetResponseDescriptions(descriptions map[int]string) *OperationBuilder {
+	for code, desc := range descriptions {
+		response := openapi3.NewResponse()
+		if existing := op.operation.Responses.Status(code); existing != nil && existing.Value != nil {
+			// the response is copied as it may be shared with the other operations of the router
+			copied := *existing.Value
+			response = &copied
+		}
+		op.operation.AddResponse(code, response.WithDescription(desc))
+	}
+	return op
+}
+
+// AddPartialContentResponse documents range requests for the operation.
+// It adds an optional Range header parameter and a 206 Partial Content response with the given media type,
+// carrying the Content-Range and Accept-Ranges headers.
+func (op *OperationBuilder) AddPartialContentResponse(model any, mediaType string, description ...string) *OperationBuilder {
+	desc := http.StatusText(http.StatusPartialContent)
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	response := op.route.gen.GenerateResponse(http.StatusPartialContent, model, mediaType, desc)
+	response.Headers = openapi3.Headers{
+		fiber.HeaderContentRange: &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "The range of the returned content, e.g. bytes 0-1023/4096",
+			Required:    true,
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}},
+		fiber.HeaderAcceptRanges: &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "The range unit supported by the server",
+			Schema:      openapi3.NewStringSchema().WithEnum("bytes").NewRef(),
+		}}},
+	}
+	op.operation.AddResponse(http.StatusPartialContent, response)
+
+	rangeParameter := openapi3.NewHeaderParameter(fiber.HeaderRange).
+		WithDescription("The range of the content to return, e.g. bytes=0-1023").
+		WithSchema(openapi3.NewStringSchema())
+	return op.AddParameter(rangeParameter)
+}
+
 // SetIgnoreAPIDoc sets whether to ignore the operation when generating the API doc.
 func (op *OperationBuilder) IgnoreAPIDoc(ignore bool) *OperationBuilder {
 	op.ignoreAPIDoc = ignore
 	return op
 }
 
+// ValidateResponses sets whether to validate the JSON responses of the operation against their documented schema.
+func (op *OperationBuilder) ValidateResponses(validate bool) *OperationBuilder {
+	op.validateResponses = validate
+	return op
+}
+
 // OnBeforeBind adds a hook that is called before binding the request.
 func (op *OperationBuilder) OnBeforeBind(hook HookBeforeBind) *OperationBuilder {
 	op.hooksBeforeBind = append(op.hooksBeforeBind, hook)
@@ -164,16 +509,115 @@ func (op *OperationBuilder) OnAfterBind(hook HookAfterBind) *OperationBuilder {
 	return op
 }
 
+// MarkSuccessResponse marks the response of the given code as the canonical success response of the operation,
+// documented with the x-success extension. It defaults to the lowest documented 2xx response.
+func (op *OperationBuilder) MarkSuccessResponse(code int) *OperationBuilder {
+	op.successCode = code
+	return op
+}
+
+// setSuccessExtension sets the x-success extension to the marked success response, or the lowest 2xx response.
+func (op *OperationBuilder) setSuccessExtension() {
+	code := op.successCode
+	if code == 0 {
+		for status := range op.operation.Responses.Map() {
+			if n, err := strconv.Atoi(status); err == nil && n >= 200 && n < 300 && (code == 0 || n < code) {
+				code = n
+			}
+		}
+	}
+	if code == 0 {
+		return
+	}
+	if op.operation.Extensions == nil {
+		op.operation.Extensions = make(map[string]any)
+	}
+	op.operation.Extensions[extSuccess] = code
+}
+
 // OK finalizes the operation building process.
 func (op *OperationBuilder) OK() {
 	if !op.ignoreAPIDoc {
+		op.operation.OperationID = op.route.gen.uniqueOperationID(op.operation.OperationID)
+		op.setSuccessExtension()
+		op.route.gen.addDefaultResponses(op.operation)
+		if !op.sunset.IsZero() {
+			op.addDeprecationHeaders()
+		}
 		path := cleanPath(op.patternFull)
 		op.route.gen.doc.AddOperation(path, op.method, op.operation)
+		op.route.gen.version.Add(1)
+		if _, ok := op.operation.Extensions[extDisplayOrder]; ok {
+			op.route.gen.sortTags()
+		}
 	}
 	handlers := append([]fiber.Handler{op.bindInput}, op.handlers...)
+	if op.validateResponses {
+		handlers = append([]fiber.Handler{op.validateResponse}, handlers...)
+	}
+	if !op.sunset.IsZero() {
+		handlers = append([]fiber.Handler{op.sendDeprecationHeaders}, handlers...)
+	}
 	op.route.Raw.Add(op.method, op.pattern, handlers...).Name(op.operation.OperationID)
 }
 
+// addDeprecationHeaders documents the Deprecation and Sunset headers on the responses of the operation.
+// The referenced responses are left as is, as they are shared with the other operations.
+func (op *OperationBuilder) addDeprecationHeaders() {
+	for code, existing := range op.operation.Responses.Map() {
+		if existing.Ref != "" || existing.Value == nil {
+			continue
+		}
+		// the response is copied as it may be shared with the other operations of the router
+		response := *existing.Value
+		response.Headers = maps.Clone(response.Headers)
+		if response.Headers == nil {
+			response.Headers = openapi3.Headers{}
+		}
+		response.Headers[HeaderDeprecation] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "Signals that the operation is deprecated",
+			Schema:      openapi3.NewStringSchema().WithEnum("true").NewRef(),
+		}}}
+		response.Headers[HeaderSunset] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "The date after which the operation is removed, " + op.sunset.UTC().Format(http.TimeFormat),
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}}
+		op.operation.Responses.Set(code, &openapi3.ResponseRef{Value: &response})
+	}
+}
+
+// sendDeprecationHeaders sets the Deprecation and Sunset headers of the response.
+func (op *OperationBuilder) sendDeprecationHeaders(ctx *fiber.Ctx) error {
+	ctx.Set(HeaderDeprecation, "true")
+	ctx.Set(HeaderSunset, op.sunset.UTC().Format(http.TimeFormat))
+	return ctx.Next()
+}
+
+// validateResponse validates the JSON response against the schema documented for its status code.
+func (op *OperationBuilder) validateResponse(ctx *fiber.Ctx) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+
+	response := op.operation.Responses.Status(ctx.Response().StatusCode())
+	if response == nil || response.Value == nil {
+		return nil
+	}
+	mediaType := response.Value.Content["application/json"]
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(ctx.Response().Body(), &value); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "invalid response: "+err.Error())
+	}
+	if err := mediaType.Schema.Value.VisitJSON(value, openapi3.VisitAsResponse()); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "invalid response: "+err.Error())
+	}
+	return nil
+}
+
 // bindInput binds the request body to the input struct.
 func (op *OperationBuilder) bindInput(ctx *fiber.Ctx) error {
 	// Execute Hooks: BeforeBind
@@ -183,7 +627,27 @@ func (op *OperationBuilder) bindInput(ctx *fiber.Ctx) error {
 		}
 	}
 
+	// With a validation error handler, the binding errors are collected and reported together.
+	var errs []*ValidationError
+	handler := op.route.gen.validationErrorHandler
+	fail := func(in string, err error) error {
+		if handler == nil {
+			return err
+		}
+		errs = append(errs, toValidationErrors(in, err)...)
+		return nil
+	}
+
+	if op.idempotencyKeyRequired && ctx.Get(HeaderIdempotencyKey) == "" {
+		if err := fail(HeaderTag, &ValidationError{In: HeaderTag, Name: HeaderIdempotencyKey, Reason: "is required"}); err != nil {
+			return err
+		}
+	}
+
 	if op.input == nil {
+		if len(errs) > 0 {
+			return handler(ctx, errs)
+		}
 		return ctx.Next()
 	}
 
@@ -191,27 +655,122 @@ func (op *OperationBuilder) bindInput(ctx *fiber.Ctx) error {
 	input := reflect.New(op.input).Interface()
 
 	// Bind the input
-	binders := []func(any) error{
-		bindPath(ctx),
-		bindHeader(ctx),
-		ctx.QueryParser,
-		ctx.CookieParser,
+	binders := []struct {
+		in   string
+		bind func(any) error
+	}{
+		{PathTag, bindPath(ctx, op.pathStyles)},
+		{HeaderTag, bindHeader(ctx, op.headerJoins)},
+		{QueryTag, bindQuery(ctx)},
+		{CookieTag, bindCookie(ctx)},
 	}
 	for _, binder := range binders {
-		if err := binder(input); err != nil {
-			return err
+		err := binder.bind(input)
+		if err != nil && len(op.contentParams) > 0 {
+			// the content parameters are left to be decoded below, the decoders cannot bind them
+			var names []string
+			for _, param := range op.contentParams {
+				if param.in == binder.in {
+					names = append(names, param.name)
+				}
+			}
+			err = dropFieldErrors(err, names)
+		}
+		if err != nil {
+			if err := fail(binder.in, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bind the array parameters by their declared serialization
+	for _, param := range op.arrayParams {
+		if err := param.bind(ctx, input); err != nil {
+			if err := fail(param.in, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bind the boolean flags present without a value
+	for _, param := range op.flagParams {
+		param.bind(ctx, input)
+	}
+
+	// Bind the content parameters, they are serialized as a whole
+	for _, param := range op.contentParams {
+		value := param.value(ctx)
+		if value == "" {
+			continue
+		}
+		field := reflect.ValueOf(input).Elem().FieldByIndex(param.index)
+		if err := json.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			if err := fail(param.in, &ValidationError{In: param.in, Name: param.name, Reason: "invalid content: " + err.Error()}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bind the bearer tokens
+	if len(op.bearerFields) > 0 {
+		token := bearerToken(ctx.Get(fiber.HeaderAuthorization))
+		for _, index := range op.bearerFields {
+			reflect.ValueOf(input).Elem().FieldByIndex(index).SetString(token)
+		}
+	}
+
+	// Check the presence of the required parameters
+	for _, param := range op.requiredParams {
+		if !param.present(ctx) {
+			if err := fail(param.in, &ValidationError{In: param.in, Name: param.name, Reason: "is required"}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate the bound parameters
+	for _, check := range op.parameterChecks {
+		if err := check.validate(reflect.ValueOf(input).Elem()); err != nil {
+			if err := fail(check.parameter.In, err); err != nil {
+				return err
+			}
 		}
 	}
 
 	// Bind the request body
-	if op.inputBodyField != "" {
+	if op.inputBodyRaw {
+		reflect.ValueOf(input).Elem().FieldByName(op.inputBodyField).SetBytes(slices.Clone(ctx.Body()))
+	} else if op.inputBodyField != "" && !(op.inputBodyOptional && len(ctx.Body()) == 0) {
 		body := reflect.New(op.inputBody).Interface()
-		if err := ctx.BodyParser(body); err != nil {
-			return err
+		var err error
+		if op.inputBodyMediaType == "json" && isFormRequest(ctx) {
+			// the JSON bodies also accepting forms bind the form values by their JSON names, as documented
+			err = bindForm(ctx, "json", body)
+		} else if isFormRequest(ctx) {
+			err = bindForm(ctx, "form", body)
+		} else {
+			err = ctx.BodyParser(body)
+		}
+		if err == nil {
+			err = bindFiles(ctx, reflect.ValueOf(body).Elem())
+		}
+		if err != nil {
+			if err := fail(inBody, err); err != nil {
+				return err
+			}
 		}
 		reflect.ValueOf(input).Elem().FieldByName(op.inputBodyField).Set(reflect.ValueOf(body).Elem())
 	}
 
+	if len(errs) > 0 {
+		return handler(ctx, errs)
+	}
+
+	// Validate the bound input as a whole
+	if err := validateInput(ctx, input); err != nil {
+		return err
+	}
+
 	// Execute Hooks: AfterBind
 	for _, hook := range op.hooksAfterBind {
 		if err := hook(ctx, input); err != nil {
@@ -223,9 +782,44 @@ func (op *OperationBuilder) bindInput(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
+// typesMu guards the custom types, the converters and the decoder pools against RegisterType.
+var typesMu sync.RWMutex
+
 var decoderPools = map[string]*sync.Pool{
 	PathTag:   {New: func() any { return buildDecoder(PathTag) }},
 	HeaderTag: {New: func() any { return buildDecoder(HeaderTag) }},
+	QueryTag:  {New: func() any { return buildDecoder(QueryTag) }},
+	CookieTag: {New: func() any { return buildDecoder(CookieTag) }},
+	"form":    {New: func() any { return buildDecoder("form") }},
+	"json":    {New: func() any { return buildDecoder("json") }},
+}
+
+// decoderPool returns the pool of the decoders matching the fields by the given tag.
+func decoderPool(tag string) *sync.Pool {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	return decoderPools[tag]
+}
+
+// decode decodes the values into out with a decoder matching the fields by the given tag.
+func decode(tag string, out any, data map[string][]string) error {
+	pool := decoderPool(tag)
+	decoder := pool.Get().(*schema.Decoder)
+	defer pool.Put(decoder)
+	return decoder.Decode(out, data)
+}
+
+// converters are the custom converters registered on the decoders, keyed by the type they decode.
+var converters = map[reflect.Type]func(string) reflect.Value{
+	wnDuration:                        convertDuration,
+	reflect.TypeOf(sql.NullString{}):  convertSQLNull[sql.NullString](),
+	reflect.TypeOf(sql.NullInt64{}):   convertSQLNull[sql.NullInt64](),
+	reflect.TypeOf(sql.NullInt32{}):   convertSQLNull[sql.NullInt32](),
+	reflect.TypeOf(sql.NullInt16{}):   convertSQLNull[sql.NullInt16](),
+	reflect.TypeOf(sql.NullByte{}):    convertSQLNull[sql.NullByte](),
+	reflect.TypeOf(sql.NullFloat64{}): convertSQLNull[sql.NullFloat64](),
+	reflect.TypeOf(sql.NullBool{}):    convertSQLNull[sql.NullBool](),
+	reflect.TypeOf(sql.NullTime{}):    convertSQLNullTime,
 }
 
 func buildDecoder(tag string) *schema.Decoder {
@@ -233,24 +827,205 @@ func buildDecoder(tag string) *schema.Decoder {
 	decoder.SetAliasTag(tag)
 	decoder.IgnoreUnknownKeys(true)
 	decoder.ZeroEmpty(true)
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	for typ, converter := range converters {
+		decoder.RegisterConverter(reflect.Zero(typ).Interface(), converter)
+	}
 	return decoder
 }
 
-func bindPath(c *fiber.Ctx) func(any) error {
+// RegisterType registers a custom type, documented with the given schema and bound from strings with the given converter.
+// It allows supporting types such as uuid.UUID without soda depending on them, e.g.:
+//
+//	soda.RegisterType(reflect.TypeOf(uuid.UUID{}), openapi3.NewUUIDSchema(), func(s string) (reflect.Value, error) {
+//		id, err := uuid.Parse(s)
+//		return reflect.ValueOf(id), err
+//	})
+//
+// It should be called before the routes using the type are registered.
+func RegisterType(typ reflect.Type, schema *openapi3.Schema, converter func(string) (reflect.Value, error)) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	customTypes[typ] = schema
+	if converter == nil {
+		return
+	}
+	converters[typ] = func(value string) reflect.Value {
+		v, err := converter(value)
+		if err != nil {
+			return reflect.Value{}
+		}
+		return v
+	}
+	// the decoders built since are dropped with their pools
+	for tag := range decoderPools {
+		decoderPools[tag] = &sync.Pool{New: func() any { return buildDecoder(tag) }}
+	}
+}
+
+// convertSQLNull returns a converter scanning strings into a valid value of the given database/sql nullable type.
+func convertSQLNull[T any, PT interface {
+	*T
+	sql.Scanner
+}]() func(string) reflect.Value {
+	return func(value string) reflect.Value {
+		var v T
+		if err := PT(&v).Scan(value); err != nil {
+			return reflect.Value{}
+		}
+		return reflect.ValueOf(v)
+	}
+}
+
+// convertSQLNullTime converts a RFC 3339 date-time string into a valid sql.NullTime.
+func convertSQLNullTime(value string) reflect.Value {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(sql.NullTime{Time: t, Valid: true})
+}
+
+// convertDuration converts a duration string such as 1h30m, an invalid value is returned on failure.
+func convertDuration(value string) reflect.Value {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(d)
+}
+
+var (
+	fileType      = reflect.PointerTo(wnFile)
+	fileSliceType = reflect.SliceOf(fileType)
+)
+
+// bindFiles binds the files uploaded with a multipart request to the *multipart.FileHeader
+// and []*multipart.FileHeader fields of the body, the other fields are bound by the body parser.
+func bindFiles(ctx *fiber.Ctx, body reflect.Value) error {
+	if body.Kind() != reflect.Struct || !strings.HasPrefix(string(ctx.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		return nil
+	}
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < body.NumField(); i++ {
+		f := body.Type().Field(i)
+		files := form.File[newTagsResolver(f).name("form")]
+		if len(files) == 0 {
+			continue
+		}
+		switch f.Type {
+		case fileType:
+			body.Field(i).Set(reflect.ValueOf(files[0]))
+		case fileSliceType:
+			body.Field(i).Set(reflect.ValueOf(files))
+		}
+	}
+	return nil
+}
+
+// isFormRequest reports whether the request body is an URL encoded or multipart form.
+func isFormRequest(ctx *fiber.Ctx) bool {
+	contentType := string(ctx.Request().Header.ContentType())
+	return strings.HasPrefix(contentType, fiber.MIMEApplicationForm) || strings.HasPrefix(contentType, fiber.MIMEMultipartForm)
+}
+
+// bindForm binds the values of the form body into out, matching the fields by the given tag.
+func bindForm(ctx *fiber.Ctx, tag string, out any) error {
+	data := make(map[string][]string)
+	if strings.HasPrefix(string(ctx.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return err
+		}
+		maps.Copy(data, form.Value)
+	} else {
+		data = collectValues(ctx, out, tag, ctx.Request().PostArgs().VisitAll)
+	}
+	return decode(tag, out, data)
+}
+
+// bindQuery binds the query parameters, as the query parser of fiber does but with the decoders of soda.
+func bindQuery(c *fiber.Ctx) func(any) error {
+	return func(out any) error {
+		return decode(QueryTag, out, collectValues(c, out, QueryTag, c.Context().QueryArgs().VisitAll))
+	}
+}
+
+// bindCookie binds the cookies, as the cookie parser of fiber does but with the decoders of soda.
+func bindCookie(c *fiber.Ctx) func(any) error {
+	return func(out any) error {
+		return decode(CookieTag, out, collectValues(c, out, CookieTag, c.Request().Header.VisitAllCookie))
+	}
+}
+
+// collectValues collects the values visited by visit, e.g. the query arguments.
+// The keys in brackets are converted to the dotted form, e.g. filter[name] to filter.name,
+// and the comma separated values are split if the app enables splitting on parsers.
+func collectValues(c *fiber.Ctx, out any, tag string, visit func(func(key, val []byte))) map[string][]string {
+	data := make(map[string][]string)
+	visit(func(key, val []byte) {
+		k := string(key)
+		v := string(val)
+		if strings.Contains(k, "[") {
+			k = parseParamSquareBrackets(k)
+		}
+		if c.App().Config().EnableSplittingOnParsers && strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k, tag) {
+			data[k] = append(data[k], strings.Split(v, ",")...)
+		} else {
+			data[k] = append(data[k], v)
+		}
+	})
+	return data
+}
+
+// parseParamSquareBrackets converts the brackets of a key to the dotted form, e.g. a[b][] to a.b.
+// steal from fiber ;)
+func parseParamSquareBrackets(k string) string {
+	var b strings.Builder
+	for i := 0; i < len(k); i++ {
+		if k[i] == '[' && i+1 < len(k) && k[i+1] != ']' {
+			b.WriteByte('.')
+		}
+		if k[i] == '[' || k[i] == ']' {
+			continue
+		}
+		b.WriteByte(k[i])
+	}
+	return b.String()
+}
+
+// bearerToken extracts the token of a bearer authorization header, an empty string is returned for other schemes.
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// bindPath binds the path parameters, the ones in styles are decoded by their declared serialization.
+func bindPath(c *fiber.Ctx, styles map[string]pathStyle) func(any) error {
 	return func(out any) error {
 		params := c.Route().Params
 		data := make(map[string][]string, len(params))
 		for _, param := range params {
+			if style, ok := styles[param]; ok {
+				data[param] = append(data[param], style.decode(c.Params(param))...)
+				continue
+			}
 			data[param] = append(data[param], c.Params(param))
 		}
 
-		pathDecoder := decoderPools[PathTag].Get().(*schema.Decoder)
-		defer decoderPools[PathTag].Put(pathDecoder)
-		return pathDecoder.Decode(out, data)
+		return decode(PathTag, out, data)
 	}
 }
 
-func bindHeader(c *fiber.Ctx) func(any) error {
+// bindHeader binds the headers, the repeated values of the headers in joins are joined with their separator.
+func bindHeader(c *fiber.Ctx, joins map[string]string) func(any) error {
 	return func(out any) error {
 		data := make(map[string][]string)
 		c.Request().Header.VisitAll(func(key, val []byte) {
@@ -266,10 +1041,13 @@ func bindHeader(c *fiber.Ctx) func(any) error {
 				data[k] = append(data[k], v)
 			}
 		})
+		for k, values := range data {
+			if sep, ok := joins[strings.ToLower(k)]; ok && len(values) > 1 {
+				data[k] = []string{strings.Join(values, sep)}
+			}
+		}
 
-		headerDecoder := decoderPools[HeaderTag].Get().(*schema.Decoder)
-		defer decoderPools[HeaderTag].Put(headerDecoder)
-		return headerDecoder.Decode(out, data)
+		return decode(HeaderTag, out, data)
 	}
 }
 
@@ -1,17 +1,57 @@
 package soda_test
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/neo-f/soda/v3"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
+type color int
+
+const (
+	colorRed color = iota + 1
+	colorGreen
+)
+
+func (c color) MarshalText() ([]byte, error) {
+	switch c {
+	case colorRed:
+		return []byte("red"), nil
+	case colorGreen:
+		return []byte("green"), nil
+	}
+	return nil, fmt.Errorf("unknown color %d", int(c))
+}
+
+func (c *color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = colorRed
+	case "green":
+		*c = colorGreen
+	default:
+		return fmt.Errorf("unknown color %q", text)
+	}
+	return nil
+}
+
 func TestOperations(t *testing.T) {
 	Convey("Given a soda engine", t, func() {
 		engine := soda.New()
@@ -128,6 +168,987 @@ func TestOperations(t *testing.T) {
 			})
 		})
 
+		Convey("When setting up an operation with a raw bytes body", func() {
+			type base64Input struct {
+				Body []byte `body:"json"`
+			}
+			type binaryInput struct {
+				Body []byte `body:"json" oai:"format=binary"`
+			}
+			engine.Post("/base64", func(c *fiber.Ctx) error {
+				return c.Send(soda.GetInput[base64Input](c).Body)
+			}).SetInput(&base64Input{}).OK()
+			engine.Post("/binary", func(c *fiber.Ctx) error {
+				return c.Send(soda.GetInput[binaryInput](c).Body)
+			}).SetInput(&binaryInput{}).OK()
+
+			Convey("Then the body should be documented as base64 by default", func() {
+				content := engine.OpenAPI().Paths.Find("/base64").Post.RequestBody.Value.Content
+				So(content.Get("application/json").Schema.Value.Format, ShouldEqual, "byte")
+			})
+
+			Convey("Then the body should be documented as binary with format=binary", func() {
+				content := engine.OpenAPI().Paths.Find("/binary").Post.RequestBody.Value.Content
+				So(content, ShouldContainKey, "application/octet-stream")
+				So(content.Get("application/octet-stream").Schema.Value.Format, ShouldEqual, "binary")
+			})
+
+			Convey("And the raw body should be bound", func() {
+				request, _ := http.NewRequest("POST", "/binary", strings.NewReader("\x00\x01raw"))
+				request.Header.Add("Content-Type", "application/octet-stream")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "\x00\x01raw")
+			})
+
+			Convey("And the base64 body should be decoded from JSON", func() {
+				request, _ := http.NewRequest("POST", "/base64", strings.NewReader(`"AAFyYXc="`))
+				request.Header.Add("Content-Type", "application/json")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "\x00\x01raw")
+			})
+		})
+
+		Convey("When accepting several media types for the body", func() {
+			type input struct {
+				Body struct {
+					Name string `json:"name" form:"name"`
+					Age  int    `json:"age"  form:"age"`
+				} `body:"json,form"`
+			}
+			engine.Post("/people", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c).Body)
+			}).SetInput(input{}).OK()
+
+			Convey("Then the request body should list both media types with the same schema", func() {
+				content := engine.OpenAPI().Paths.Find("/people").Post.RequestBody.Value.Content
+				So(content, ShouldHaveLength, 2)
+				So(content, ShouldContainKey, "application/json")
+				So(content, ShouldContainKey, "application/x-www-form-urlencoded")
+				So(content["application/x-www-form-urlencoded"].Schema, ShouldEqual, content["application/json"].Schema)
+			})
+
+			Convey("And posting form data should bind the body", func() {
+				request, _ := http.NewRequest("POST", "/people", strings.NewReader("name=soda&age=3"))
+				request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"name":"soda","age":3}`)
+			})
+
+			Convey("And posting JSON should bind the body", func() {
+				request, _ := http.NewRequest("POST", "/people", strings.NewReader(`{"name":"soda","age":3}`))
+				request.Header.Set("Content-Type", "application/json")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"name":"soda","age":3}`)
+			})
+		})
+
+		Convey("When a JSON body falls back to form values", func() {
+			type input struct {
+				Body struct {
+					FirstName string   `json:"first_name"`
+					Age       int      `json:"age,omitempty"`
+					Tags      []string `json:"tags"`
+				} `body:"json,form,multipart"`
+			}
+			engine.Post("/members", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c).Body)
+			}).SetInput(input{}).OK()
+			expect := `{"first_name":"soda","age":3,"tags":["a","b"]}`
+
+			post := func(body io.Reader, contentType string) string {
+				request, _ := http.NewRequest("POST", "/members", body)
+				request.Header.Set("Content-Type", contentType)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusOK)
+				data, _ := io.ReadAll(response.Body)
+				return string(data)
+			}
+
+			Convey("Then posting JSON should bind the struct", func() {
+				So(post(strings.NewReader(expect), "application/json"), ShouldEqual, expect)
+			})
+
+			Convey("Then posting a form should bind the same struct by the JSON names", func() {
+				form := strings.NewReader("first_name=soda&age=3&tags=a&tags=b")
+				So(post(form, "application/x-www-form-urlencoded"), ShouldEqual, expect)
+			})
+
+			Convey("Then posting a multipart form should bind the same struct by the JSON names", func() {
+				var buf bytes.Buffer
+				writer := multipart.NewWriter(&buf)
+				_ = writer.WriteField("first_name", "soda")
+				_ = writer.WriteField("age", "3")
+				_ = writer.WriteField("tags", "a")
+				_ = writer.WriteField("tags", "b")
+				_ = writer.Close()
+				So(post(&buf, writer.FormDataContentType()), ShouldEqual, expect)
+			})
+		})
+
+		Convey("When uploading files with a multipart body", func() {
+			type input struct {
+				Body struct {
+					Title       string                  `form:"title"`
+					Avatar      *multipart.FileHeader   `form:"avatar"`
+					Attachments []*multipart.FileHeader `form:"attachments"`
+				} `body:"multipart"`
+			}
+			engine.Post("/uploads", func(c *fiber.Ctx) error {
+				body := soda.GetInput[input](c).Body
+				return c.JSON(map[string]any{
+					"title":       body.Title,
+					"avatar":      body.Avatar.Filename,
+					"attachments": len(body.Attachments),
+				})
+			}).SetInput(input{}).OK()
+
+			Convey("Then the spec should document the files as binary strings", func() {
+				content := engine.OpenAPI().Paths.Find("/uploads").Post.RequestBody.Value.Content
+				So(content, ShouldContainKey, "multipart/form-data")
+				schema := content["multipart/form-data"].Schema.Value
+				So(schema.Properties["avatar"].Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("binary"))
+				So(schema.Properties["attachments"].Value.Items.Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("binary"))
+				So(schema.Properties, ShouldContainKey, "title")
+			})
+
+			Convey("And the files and text fields should be bound", func() {
+				payload := &bytes.Buffer{}
+				writer := multipart.NewWriter(payload)
+				_ = writer.WriteField("title", "holiday")
+				avatar, _ := writer.CreateFormFile("avatar", "me.png")
+				_, _ = avatar.Write([]byte("png"))
+				for _, name := range []string{"a.txt", "b.txt"} {
+					attachment, _ := writer.CreateFormFile("attachments", name)
+					_, _ = attachment.Write([]byte(name))
+				}
+				_ = writer.Close()
+
+				request, _ := http.NewRequest("POST", "/uploads", payload)
+				request.Header.Set("Content-Type", writer.FormDataContentType())
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"attachments":2,"avatar":"me.png","title":"holiday"}`)
+			})
+		})
+
+		Convey("When using a custom media type alias", func() {
+			soda.RegisterMediaTypeAlias("hal", "application/hal+json")
+			type resource struct {
+				Name string `json:"name"`
+			}
+			type input struct {
+				Body resource `body:"hal"`
+			}
+			engine.Put("/resources", func(c *fiber.Ctx) error { return nil }).
+				SetInput(input{}).
+				AddResponse(200, resource{}, "hal").
+				AddResponse(201, resource{}, "json").
+				OK()
+			operation := engine.OpenAPI().Paths.Find("/resources").Put
+
+			Convey("Then the responses should use the aliased media types", func() {
+				hal := operation.Responses.Status(200).Value.Content
+				So(hal, ShouldContainKey, "application/hal+json")
+				So(hal["application/hal+json"].Schema.Value.Properties, ShouldContainKey, "name")
+				So(operation.Responses.Status(201).Value.Content, ShouldContainKey, "application/json")
+			})
+
+			Convey("And the request body should use the aliased media type", func() {
+				content := operation.RequestBody.Value.Content
+				So(content, ShouldHaveLength, 1)
+				So(content["application/hal+json"].Schema.Value.Properties, ShouldContainKey, "name")
+			})
+		})
+
+		Convey("When validating the responses of an operation", func() {
+			type output struct {
+				A int `json:"a"`
+			}
+			invalidHandler := func(c *fiber.Ctx) error {
+				return c.JSON(map[string]any{"a": "not a number"})
+			}
+			engine.Get("/validated", invalidHandler).AddJSONResponse(200, output{}).ValidateResponses(true).OK()
+			engine.Get("/unvalidated", invalidHandler).AddJSONResponse(200, output{}).OK()
+			engine.Get("/valid", func(c *fiber.Ctx) error {
+				return c.JSON(output{A: 1})
+			}).AddJSONResponse(200, output{}).ValidateResponses(true).OK()
+
+			Convey("Then an invalid response of the flagged operation should be rejected", func() {
+				request, _ := http.NewRequest("GET", "/validated", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 500)
+			})
+
+			Convey("Then a valid response of the flagged operation should pass", func() {
+				request, _ := http.NewRequest("GET", "/valid", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+			})
+
+			Convey("Then other operations should not be validated", func() {
+				request, _ := http.NewRequest("GET", "/unvalidated", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+			})
+		})
+
+		Convey("When adding a manual parameter", func() {
+			type input struct {
+				Page int `query:"page"`
+			}
+			filter := openapi3.NewQueryParameter("filter").
+				WithSchema(openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema()))
+			engine.Get("/manual", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c))
+			}).
+				AddParameter(filter).
+				SetInput(&input{}).
+				OK()
+
+			Convey("Then the manual parameter should coexist with the input parameters", func() {
+				parameters := engine.OpenAPI().Paths.Find("/manual").Get.Parameters
+				So(parameters, ShouldHaveLength, 2)
+				So(parameters.GetByInAndName("query", "page"), ShouldNotBeNil)
+				So(parameters.GetByInAndName("query", "filter"), ShouldEqual, filter)
+			})
+
+			Convey("And the binder should ignore the manual parameter", func() {
+				request, _ := http.NewRequest("GET", "/manual?page=2&filter=x", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"Page":2}`)
+			})
+		})
+
+		Convey("When adding a request header parameter", func() {
+			type input struct {
+				Page int `query:"page"`
+			}
+			engine.Get("/exports", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c))
+			}).
+				SetInput(&input{}).
+				AddRequestHeaderParam("X-Tenant", true, "The tenant of the export").
+				OK()
+
+			Convey("Then the header parameter should be documented next to the input parameters", func() {
+				parameters := engine.OpenAPI().Paths.Find("/exports").Get.Parameters
+				So(parameters, ShouldHaveLength, 2)
+				header := parameters.GetByInAndName("header", "X-Tenant")
+				So(header, ShouldNotBeNil)
+				So(header.Required, ShouldBeTrue)
+				So(header.Description, ShouldEqual, "The tenant of the export")
+				So(header.Schema.Value, ShouldResemble, openapi3.NewStringSchema())
+			})
+
+			Convey("And the binder should tolerate the unbound header", func() {
+				request, _ := http.NewRequest("GET", "/exports?page=3", nil)
+				request.Header.Set("X-Tenant", "acme")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"Page":3}`)
+			})
+		})
+
+		Convey("When retrieving the input safely", func() {
+			type input struct {
+				Page int `query:"page"`
+			}
+			type other struct{}
+			var (
+				found, mismatched bool
+				bound             *input
+				message           string
+			)
+			engine.Get("/no-input", func(c *fiber.Ctx) error {
+				_, found = soda.GetInputOK[input](c)
+				defer func() { message, _ = recover().(string) }()
+				soda.GetInput[input](c)
+				return nil
+			}).OK()
+			engine.Get("/mismatched", func(c *fiber.Ctx) error {
+				_, mismatched = soda.GetInputOK[other](c)
+				bound, _ = soda.GetInputOK[input](c)
+				defer func() { message, _ = recover().(string) }()
+				soda.GetInput[other](c)
+				return nil
+			}).SetInput(&input{}).OK()
+
+			Convey("Then a route without input should report false", func() {
+				request, _ := http.NewRequest("GET", "/no-input", nil)
+				_, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(found, ShouldBeFalse)
+				So(message, ShouldEqual, "soda: no input of type *soda_test.input bound, the route must be registered with SetInput")
+			})
+
+			Convey("And a mismatched type should report false", func() {
+				request, _ := http.NewRequest("GET", "/mismatched?page=1", nil)
+				_, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(mismatched, ShouldBeFalse)
+				So(bound.Page, ShouldEqual, 1)
+				So(message, ShouldEqual, "soda: input is of type *soda_test.input, not *soda_test.other")
+			})
+		})
+
+		Convey("When declaring an idempotency key", func() {
+			engine.Post("/payments", func(c *fiber.Ctx) error {
+				return c.SendString(c.Get(soda.HeaderIdempotencyKey))
+			}).SetIdempotencyKeyParam(true).OK()
+			engine.Post("/refunds", func(c *fiber.Ctx) error { return nil }).SetIdempotencyKeyParam(false).OK()
+
+			Convey("Then the header parameter should be documented", func() {
+				parameter := engine.OpenAPI().Paths.Find("/payments").Post.Parameters.GetByInAndName("header", "Idempotency-Key")
+				So(parameter, ShouldNotBeNil)
+				So(parameter.Required, ShouldBeTrue)
+				So(parameter.Schema.Value, ShouldResemble, openapi3.NewStringSchema())
+				optional := engine.OpenAPI().Paths.Find("/refunds").Post.Parameters.GetByInAndName("header", "Idempotency-Key")
+				So(optional.Required, ShouldBeFalse)
+			})
+
+			Convey("And a request without the key should be rejected when required", func() {
+				request, _ := http.NewRequest("POST", "/payments", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 422)
+
+				request, _ = http.NewRequest("POST", "/refunds", nil)
+				response, err = engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+			})
+
+			Convey("And a request with the key should pass", func() {
+				request, _ := http.NewRequest("POST", "/payments", nil)
+				request.Header.Set("Idempotency-Key", "abc")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "abc")
+			})
+		})
+
+		Convey("When documenting response headers", func() {
+			type user struct {
+				Name string `json:"name"`
+			}
+			engine.Get("/paged", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(200, []user{}).
+				AddResponseHeader(200, "X-Total-Count", 0, "The total number of users").
+				AddResponseHeader(201, "Location", "", "The URL of the created user").
+				OK()
+			responses := engine.OpenAPI().Paths.Find("/paged").Get.Responses
+
+			Convey("Then the headers should appear under their status code", func() {
+				header := responses.Status(200).Value.Headers["X-Total-Count"].Value
+				So(header.Description, ShouldEqual, "The total number of users")
+				So(header.Schema.Value, ShouldResemble, openapi3.NewIntegerSchema())
+				So(responses.Status(200).Value.Content, ShouldContainKey, "application/json")
+			})
+
+			Convey("And the response should be created if it is not declared", func() {
+				created := responses.Status(201).Value
+				So(*created.Description, ShouldEqual, "Created")
+				So(created.Headers["Location"].Value.Schema.Value, ShouldResemble, openapi3.NewStringSchema())
+			})
+		})
+
+		Convey("When setting response descriptions", func() {
+			engine.Get("/reports", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(200, []string{}).
+				AddJSONResponse(404, nil).
+				SetResponseDescriptions(map[int]string{
+					200: "The reports",
+					404: "No report found",
+					403: "Reports are restricted",
+				}).
+				OK()
+			responses := engine.OpenAPI().Paths.Find("/reports").Get.Responses
+
+			Convey("Then each description should be applied", func() {
+				So(*responses.Status(200).Value.Description, ShouldEqual, "The reports")
+				So(*responses.Status(404).Value.Description, ShouldEqual, "No report found")
+				So(*responses.Status(403).Value.Description, ShouldEqual, "Reports are restricted")
+			})
+
+			Convey("And the documented content should be kept", func() {
+				So(responses.Status(200).Value.Content, ShouldContainKey, "application/json")
+				So(responses.Status(403).Value.Content, ShouldBeNil)
+			})
+		})
+
+		Convey("When marking the success response", func() {
+			engine.Post("/orders", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(400, nil).
+				AddJSONResponse(202, nil).
+				AddJSONResponse(201, nil).
+				OK()
+			engine.Put("/orders", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(200, nil).
+				AddJSONResponse(204, nil).
+				MarkSuccessResponse(204).
+				OK()
+			engine.Delete("/orders", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(404, nil).OK()
+			item := engine.OpenAPI().Paths.Find("/orders")
+
+			Convey("Then the lowest 2xx response should be the default", func() {
+				So(item.Post.Extensions["x-success"], ShouldEqual, 201)
+			})
+
+			Convey("Then the marked response should be used", func() {
+				So(item.Put.Extensions["x-success"], ShouldEqual, 204)
+			})
+
+			Convey("Then operations without a 2xx response should not be marked", func() {
+				So(item.Delete.Extensions, ShouldNotContainKey, "x-success")
+			})
+		})
+
+		Convey("When adding list responses", func() {
+			type listUser struct {
+				Name string `json:"name"`
+			}
+			models := map[string]any{
+				"/slice":            []listUser{},
+				"/pointer-to-slice": &[]listUser{},
+				"/slice-of-pointer": []*listUser{},
+			}
+			for path, model := range models {
+				engine.Get(path, func(c *fiber.Ctx) error { return nil }).
+					AddJSONResponse(200, model).
+					AddResponse(206, model, "application/x-ndjson").
+					OK()
+			}
+
+			Convey("Then they should all produce the same array schema", func() {
+				ref := "#/components/schemas/soda_test.listUser"
+				for path := range models {
+					responses := engine.OpenAPI().Paths.Find(path).Get.Responses
+					schema := responses.Status(200).Value.Content["application/json"].Schema
+					So(schema.Ref, ShouldBeEmpty)
+					So(schema.Value.Type.Is("array"), ShouldBeTrue)
+					So(schema.Value.Items.Ref, ShouldEqual, ref)
+
+					stream := responses.Status(206).Value.Content["application/x-ndjson"].Schema
+					So(stream.Ref, ShouldEqual, ref)
+				}
+				So(engine.OpenAPI().Components.Schemas, ShouldContainKey, "soda_test.listUser")
+			})
+		})
+
+		Convey("When adding problem details responses", func() {
+			engine.Get("/problems", func(c *fiber.Ctx) error {
+				return soda.SendProblem(c, soda.Problem{Status: 404, Detail: "no such problem"})
+			}).AddJSONResponse(200, nil).AddProblemResponse(404).AddProblemResponse(500, "Unexpected error").OK()
+			responses := engine.OpenAPI().Paths.Find("/problems").Get.Responses
+
+			Convey("Then they should reference the problem schema with the problem+json content type", func() {
+				for code, desc := range map[int]string{404: "Not Found", 500: "Unexpected error"} {
+					response := responses.Status(code).Value
+					So(*response.Description, ShouldEqual, desc)
+					So(response.Content, ShouldHaveLength, 1)
+					So(response.Content["application/problem+json"].Schema.Ref, ShouldEqual, "#/components/schemas/Problem")
+				}
+			})
+
+			Convey("Then the problem schema should have the standard members", func() {
+				schema := engine.OpenAPI().Components.Schemas["Problem"].Value
+				So(schema.Properties, ShouldHaveLength, 5)
+				for _, name := range []string{"type", "title", "status", "detail", "instance"} {
+					So(schema.Properties, ShouldContainKey, name)
+				}
+				So(schema.Required, ShouldBeEmpty)
+				So(schema.Properties["type"].Value.Default, ShouldEqual, "about:blank")
+				So(schema.Properties["status"].Value.Type.Is("integer"), ShouldBeTrue)
+			})
+
+			Convey("Then SendProblem should respond with the problem details", func() {
+				request, _ := http.NewRequest("GET", "/problems", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 404)
+				So(response.Header.Get("Content-Type"), ShouldEqual, "application/problem+json")
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"title":"Not Found","status":404,"detail":"no such problem"}`)
+			})
+		})
+
+		Convey("When setting the display order of the operations", func() {
+			handler := func(c *fiber.Ctx) error { return nil }
+			engine.Get("/pets", handler).AddTags("pets").SetDisplayOrder(3).OK()
+			engine.Get("/misc", handler).AddTags("misc").OK()
+			engine.Get("/users", handler).AddTags("users").SetDisplayOrder(2).OK()
+			engine.Post("/users", handler).AddTags("users").SetDisplayOrder(1).OK()
+
+			Convey("Then the extension should be emitted", func() {
+				item := engine.OpenAPI().Paths.Find("/users")
+				So(item.Get.Extensions["x-displayOrder"], ShouldEqual, 2)
+				So(item.Post.Extensions["x-displayOrder"], ShouldEqual, 1)
+				spec, err := engine.OpenAPI().MarshalJSON()
+				So(err, ShouldBeNil)
+				So(string(spec), ShouldContainSubstring, `"x-displayOrder":3`)
+			})
+
+			Convey("Then the tags should be ordered by the lowest order of their operations", func() {
+				var names []string
+				for _, tag := range engine.OpenAPI().Tags {
+					names = append(names, tag.Name)
+				}
+				So(names, ShouldResemble, []string{"users", "pets", "misc"})
+			})
+		})
+
+		Convey("When adding vendor extensions", func() {
+			engine.Get("/internal", func(c *fiber.Ctx) error { return nil }).
+				AddExtension("x-internal", true).
+				AddExtension("x-codegen", map[string]any{"skip": true}).
+				OK()
+
+			Convey("Then they should appear in the spec", func() {
+				spec, err := engine.OpenAPI().MarshalJSON()
+				So(err, ShouldBeNil)
+				So(string(spec), ShouldContainSubstring, `"x-codegen":{"skip":true},"x-internal":true`)
+			})
+
+			Convey("Then keys without the x- prefix should be rejected", func() {
+				So(func() { engine.Get("/invalid", nil).AddExtension("internal", true) }, ShouldPanic)
+			})
+		})
+
+		Convey("When adding a wildcard response", func() {
+			engine.Get("/download", func(c *fiber.Ctx) error { return nil }).
+				AddResponse(200, []byte{}, "*/*", "any file").
+				OK()
+
+			Convey("Then the wildcard content should be documented", func() {
+				response := engine.OpenAPI().Paths.Find("/download").Get.Responses.Status(200).Value
+				So(*response.Description, ShouldEqual, "any file")
+				So(response.Content, ShouldContainKey, "*/*")
+			})
+		})
+
+		Convey("When adding responses of several media types", func() {
+			type user struct {
+				Name string `json:"name" xml:"full-name"`
+			}
+			engine.Get("/users/export", func(c *fiber.Ctx) error { return nil }).
+				AddJSONResponse(200, user{}).
+				AddResponse(200, user{}, "application/xml").
+				AddResponse(201, "", "text/plain").
+				AddResponse(202, []byte{}, "application/octet-stream").
+				OK()
+			responses := engine.OpenAPI().Paths.Find("/users/export").Get.Responses
+
+			Convey("Then the media types of the same code should be accumulated", func() {
+				content := responses.Status(200).Value.Content
+				So(content, ShouldHaveLength, 2)
+				So(content["application/json"].Schema.Ref, ShouldNotBeEmpty)
+				So(content["application/xml"].Schema.Ref, ShouldEqual, "#/components/schemas/soda_test.user-xml")
+				So(content["application/xml"].Schema.Value.Properties, ShouldContainKey, "full-name")
+				So(engine.OpenAPI().Components.Schemas["soda_test.user"].Value.Properties, ShouldContainKey, "name")
+			})
+
+			Convey("And plain text and binary responses should be documented as strings", func() {
+				So(responses.Status(201).Value.Content["text/plain"].Schema.Value, ShouldResemble, openapi3.NewStringSchema())
+				So(responses.Status(202).Value.Content["application/octet-stream"].Schema.Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("binary"))
+			})
+		})
+
+		Convey("When adding a partial content response", func() {
+			engine.Get("/video", func(c *fiber.Ctx) error { return nil }).
+				AddResponse(200, []byte{}, "*/*").
+				AddPartialContentResponse([]byte{}, "*/*").
+				OK()
+
+			Convey("Then the 206 response and its headers should be documented", func() {
+				operation := engine.OpenAPI().Paths.Find("/video").Get
+				response := operation.Responses.Status(206).Value
+				So(*response.Description, ShouldEqual, "Partial Content")
+				So(response.Content, ShouldContainKey, "*/*")
+				So(response.Headers, ShouldContainKey, "Content-Range")
+				So(response.Headers["Content-Range"].Value.Required, ShouldBeTrue)
+				So(response.Headers, ShouldContainKey, "Accept-Ranges")
+			})
+
+			Convey("Then the Range header parameter should be documented", func() {
+				operation := engine.OpenAPI().Paths.Find("/video").Get
+				parameter := operation.Parameters.GetByInAndName("header", "Range")
+				So(parameter, ShouldNotBeNil)
+				So(parameter.Required, ShouldBeFalse)
+			})
+		})
+
+		Convey("When referencing a registered response", func() {
+			type notFound struct {
+				Message string `json:"message"`
+			}
+			engine.RegisterResponse("NotFound", 404, notFound{}, "The resource was not found")
+			engine.Get("/orders/:id", func(c *fiber.Ctx) error { return nil }).AddResponseRef(404, "NotFound").OK()
+			engine.Get("/invoices/:id", func(c *fiber.Ctx) error { return nil }).AddResponseRef(404, "NotFound").OK()
+
+			Convey("Then the response component should exist once", func() {
+				So(engine.OpenAPI().Components.Responses, ShouldHaveLength, 1)
+				response := engine.OpenAPI().Components.Responses["NotFound"].Value
+				So(*response.Description, ShouldEqual, "The resource was not found")
+				So(response.Content["application/json"].Schema.Ref, ShouldEqual, "#/components/schemas/soda_test.notFound")
+			})
+
+			Convey("And the operations should reference it", func() {
+				for _, path := range []string{"/orders/:id", "/invoices/:id"} {
+					response := engine.OpenAPI().Paths.Find(path).Get.Responses.Status(404)
+					So(response.Ref, ShouldEqual, "#/components/responses/NotFound")
+					data, _ := response.MarshalJSON()
+					So(string(data), ShouldEqual, `{"$ref":"#/components/responses/NotFound"}`)
+				}
+			})
+
+			Convey("Then referencing an unregistered response should panic", func() {
+				So(func() { engine.Delete("/orders/:id", nil).AddResponseRef(404, "Unknown") }, ShouldPanic)
+			})
+		})
+
+		Convey("When deprecating an operation with a sunset date", func() {
+			sunset := time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC)
+			engine.Get("/legacy", func(c *fiber.Ctx) error {
+				return c.JSON(map[string]string{"status": "ok"})
+			}).SetDeprecatedWithSunset(sunset).AddJSONResponse(200, map[string]string{}).OK()
+			engine.Get("/current", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, map[string]string{}).OK()
+
+			Convey("Then the responses should carry the Deprecation and Sunset headers", func() {
+				request, _ := http.NewRequest("GET", "/legacy", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				So(response.Header.Get("Deprecation"), ShouldEqual, "true")
+				So(response.Header.Get("Sunset"), ShouldEqual, "Wed, 02 Jan 2030 15:04:05 GMT")
+			})
+
+			Convey("And the headers should be documented on the responses", func() {
+				operation := engine.OpenAPI().Paths.Find("/legacy").Get
+				So(operation.Deprecated, ShouldBeTrue)
+				headers := operation.Responses.Status(200).Value.Headers
+				So(headers, ShouldContainKey, soda.HeaderDeprecation)
+				So(headers, ShouldContainKey, soda.HeaderSunset)
+			})
+
+			Convey("And the other operations should be left as is", func() {
+				request, _ := http.NewRequest("GET", "/current", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.Header.Get("Deprecation"), ShouldBeEmpty)
+				So(engine.OpenAPI().Paths.Find("/current").Get.Responses.Status(200).Value.Headers, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When referencing a defined request body", func() {
+			type user struct {
+				Name string `json:"name"`
+			}
+			type input struct {
+				Body user `body:"json"`
+			}
+			engine.DefineRequestBody("User", "application/json", user{})
+			engine.Post("/users", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c).Body)
+			}).SetRequestBodyRef("User").SetInput(&input{}).OK()
+			engine.Put("/users", func(c *fiber.Ctx) error { return nil }).SetRequestBodyRef("User").OK()
+
+			Convey("Then the operations should reference the single component", func() {
+				So(engine.OpenAPI().Components.RequestBodies, ShouldHaveLength, 1)
+				So(engine.OpenAPI().Paths.Find("/users").Post.RequestBody.Ref, ShouldEqual, "#/components/requestBodies/User")
+				So(engine.OpenAPI().Paths.Find("/users").Put.RequestBody.Ref, ShouldEqual, "#/components/requestBodies/User")
+			})
+
+			Convey("And the body should still be bound", func() {
+				request, _ := http.NewRequest("POST", "/users", strings.NewReader(`{"name":"soda"}`))
+				request.Header.Add("Content-Type", "application/json")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"name":"soda"}`)
+			})
+
+			Convey("Then referencing an undefined request body should panic", func() {
+				So(func() { engine.Delete("/users", nil).SetRequestBodyRef("Unknown") }, ShouldPanic)
+			})
+		})
+
+		Convey("When declaring an optional request body", func() {
+			type patch struct {
+				Name string `json:"name"`
+			}
+			type input struct {
+				Body patch `body:"json,optional"`
+			}
+			type pointerInput struct {
+				Body *patch `body:"json"`
+			}
+			engine.Patch("/profile", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c).Body)
+			}).SetInput(&input{}).OK()
+			engine.Patch("/settings", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[pointerInput](c).Body)
+			}).SetInput(&pointerInput{}).OK()
+
+			Convey("Then the request bodies should not be required", func() {
+				profile := engine.OpenAPI().Paths.Find("/profile").Patch.RequestBody.Value
+				So(profile.Required, ShouldBeFalse)
+				So(profile.Content, ShouldContainKey, "application/json")
+				So(engine.OpenAPI().Paths.Find("/settings").Patch.RequestBody.Value.Required, ShouldBeFalse)
+			})
+
+			Convey("And a request without a body should succeed", func() {
+				request, _ := http.NewRequest("PATCH", "/profile", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"name":""}`)
+
+				request, _ = http.NewRequest("PATCH", "/settings", nil)
+				response, err = engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ = io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "null")
+			})
+
+			Convey("And a request with a body should still be bound", func() {
+				request, _ := http.NewRequest("PATCH", "/settings", strings.NewReader(`{"name":"soda"}`))
+				request.Header.Add("Content-Type", "application/json")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"name":"soda"}`)
+			})
+		})
+
+		Convey("When a struct query parameter uses the deep object style", func() {
+			type filter struct {
+				Name string `query:"name"`
+				Age  int    `query:"age"`
+			}
+			type input struct {
+				Filter filter `query:"filter" oai:"style=deepObject;explode=true"`
+				Limit  int    `query:"limit"`
+			}
+			type output struct {
+				Filter filter `json:"filter"`
+			}
+			var bound *input
+			engine.Get("/search", func(c *fiber.Ctx) error {
+				bound = soda.GetInput[input](c)
+				return nil
+			}).SetInput(&input{}).AddJSONResponse(200, output{}).OK()
+
+			Convey("Then the parameter should be documented as a single object", func() {
+				parameter := engine.OpenAPI().Paths.Find("/search").Get.Parameters.GetByInAndName("query", "filter")
+				So(parameter, ShouldNotBeNil)
+				So(parameter.Style, ShouldEqual, "deepObject")
+				So(*parameter.Explode, ShouldBeTrue)
+				So(parameter.Schema.Ref, ShouldEqual, "#/components/schemas/soda_test.filter-query")
+				So(parameter.Schema.Value.Type.Is("object"), ShouldBeTrue)
+				So(parameter.Schema.Value.Properties, ShouldContainKey, "name")
+				So(parameter.Schema.Value.Properties, ShouldContainKey, "age")
+			})
+
+			Convey("Then the JSON schema of the model should be kept apart", func() {
+				So(engine.OpenAPI().Components.Schemas["soda_test.filter"].Value.Properties, ShouldContainKey, "Name")
+			})
+
+			Convey("Then the bracketed query keys should be bound into it", func() {
+				request, _ := http.NewRequest("GET", "/search?filter[name]=foo&filter[age]=1&limit=2", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				So(bound, ShouldResemble, &input{Filter: filter{Name: "foo", Age: 1}, Limit: 2})
+			})
+		})
+
+		Convey("When streaming an NDJSON response", func() {
+			type item struct {
+				ID int `json:"id"`
+			}
+			engine.Get("/export", func(c *fiber.Ctx) error {
+				ch := make(chan item)
+				go func() {
+					defer close(ch)
+					for i := 1; i <= 3; i++ {
+						ch <- item{ID: i}
+					}
+				}()
+				return soda.StreamNDJSON(c, ch)
+			}).AddResponse(200, []item{}, "application/x-ndjson").OK()
+
+			Convey("Then the item schema should be documented", func() {
+				response := engine.OpenAPI().Paths.Find("/export").Get.Responses.Status(200).Value
+				So(response.Content, ShouldContainKey, "application/x-ndjson")
+				So(response.Content["application/x-ndjson"].Schema.Value.Properties, ShouldContainKey, "id")
+			})
+
+			Convey("And the values should be streamed line by line", func() {
+				request, _ := http.NewRequest("GET", "/export", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.Header.Get("Content-Type"), ShouldEqual, "application/x-ndjson")
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+			})
+		})
+
+		Convey("When adding named JSON responses", func() {
+			type user struct {
+				Name string `json:"name"`
+			}
+			engine.Post("/users", func(c *fiber.Ctx) error { return nil }).AddJSONResponseNamed(201, "UserCreated", user{}).OK()
+			engine.Put("/users", func(c *fiber.Ctx) error { return nil }).AddJSONResponseNamed(200, "UserUpdated", user{}).OK()
+
+			Convey("Then the schemas should be registered under the chosen names", func() {
+				So(engine.OpenAPI().Components.Schemas, ShouldContainKey, "UserCreated")
+				So(engine.OpenAPI().Components.Schemas, ShouldContainKey, "UserUpdated")
+				created := engine.OpenAPI().Paths.Find("/users").Post.Responses.Status(201).Value
+				So(created.Content.Get("application/json").Schema.Ref, ShouldEqual, "#/components/schemas/UserCreated")
+				updated := engine.OpenAPI().Paths.Find("/users").Put.Responses.Status(200).Value
+				So(updated.Content.Get("application/json").Schema.Ref, ShouldEqual, "#/components/schemas/UserUpdated")
+			})
+		})
+
+		Convey("When requiring OAuth2 scopes", func() {
+			flows := soda.NewOAuth2AuthorizationCodeFlows(
+				"https://auth.example.com/authorize",
+				"https://auth.example.com/token",
+				map[string]string{"read:users": "read the users", "write:users": "modify the users"},
+			)
+			engine.Delete("/users/:id", func(c *fiber.Ctx) error { return nil }).
+				AddSecurity("oauth2", soda.NewOAuth2SecurityScheme(flows)).
+				AddSecurity("oidc", soda.NewOpenIDConnectSecurityScheme("https://auth.example.com/.well-known/openid-configuration"), "openid").
+				OK()
+			operation := engine.OpenAPI().Paths.Find("/users/:id").Delete
+
+			Convey("Then the security schemes should be registered", func() {
+				schemes := engine.OpenAPI().Components.SecuritySchemes
+				So(schemes["oauth2"].Value.Type, ShouldEqual, "oauth2")
+				So(schemes["oauth2"].Value.Flows.AuthorizationCode.TokenURL, ShouldEqual, "https://auth.example.com/token")
+				So(schemes["oauth2"].Value.Flows.AuthorizationCode.Scopes, ShouldContainKey, "write:users")
+				So(schemes["oidc"].Value.Type, ShouldEqual, "openIdConnect")
+				So(schemes["oidc"].Value.OpenIdConnectUrl, ShouldEqual, "https://auth.example.com/.well-known/openid-configuration")
+				So(schemes["oauth2"].Value.Validate(context.Background()), ShouldBeNil)
+				So(schemes["oidc"].Value.Validate(context.Background()), ShouldBeNil)
+			})
+
+			Convey("And the operation should list the required scopes", func() {
+				So(*operation.Security, ShouldResemble, openapi3.SecurityRequirements{
+					{"oauth2": []string{}},
+					{"oidc": []string{"openid"}},
+				})
+			})
+		})
+
+		Convey("When binding a bearer token", func() {
+			type input struct {
+				Token string `oai:"from=bearer"`
+				Page  int    `query:"page"`
+			}
+			engine.Get("/me", func(c *fiber.Ctx) error {
+				return c.SendString(soda.GetInput[input](c).Token)
+			}).SetInput(input{}).OK()
+			engine.Get("/public", func(c *fiber.Ctx) error { return nil }).OK()
+
+			Convey("Then the token should be documented as a security requirement", func() {
+				operation := engine.OpenAPI().Paths.Find("/me").Get
+				So(operation.Parameters, ShouldHaveLength, 1)
+				So(operation.Parameters[0].Value.In, ShouldEqual, "query")
+				So(*operation.Security, ShouldResemble, openapi3.SecurityRequirements{{soda.BearerSecurityName: []string{}}})
+				So(engine.OpenAPI().Components.SecuritySchemes[soda.BearerSecurityName].Value.Scheme, ShouldEqual, "bearer")
+				So(*engine.OpenAPI().Paths.Find("/public").Get.Security, ShouldBeEmpty)
+			})
+
+			Convey("And the token should be extracted without the Bearer prefix", func() {
+				request, _ := http.NewRequest("GET", "/me", nil)
+				request.Header.Add("Authorization", "Bearer XXX")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, "XXX")
+			})
+
+			Convey("And other authorization schemes should be ignored", func() {
+				request, _ := http.NewRequest("GET", "/me", nil)
+				request.Header.Add("Authorization", "Basic dXNlcjpwYXNz")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When binding repeated headers into a string", func() {
+			type input struct {
+				Forwarded string   `header:"X-Forwarded-For" oai:"joinWith=,"`
+				Tags      string   `header:"X-Tag"`
+				Values    []string `header:"X-Value"`
+			}
+			engine.Get("/joined", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c))
+			}).SetInput(input{}).OK()
+
+			Convey("Then the values should be joined with the separator", func() {
+				request, _ := http.NewRequest("GET", "/joined", nil)
+				request.Header.Add("X-Forwarded-For", "10.0.0.1")
+				request.Header.Add("X-Forwarded-For", "10.0.0.2")
+				request.Header.Add("X-Tag", "a")
+				request.Header.Add("X-Value", "1")
+				request.Header.Add("X-Value", "2")
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, 200)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `{"Forwarded":"10.0.0.1,10.0.0.2","Tags":"a","Values":["1","2"]}`)
+			})
+		})
+
+		Convey("When binding a text unmarshaler", func() {
+			type input struct {
+				Color color `query:"color"`
+			}
+			engine.Get("/paint", func(c *fiber.Ctx) error {
+				return c.JSON(soda.GetInput[input](c).Color)
+			}).SetInput(input{}).OK()
+
+			Convey("Then it should be documented as a string", func() {
+				parameter := engine.OpenAPI().Paths.Find("/paint").Get.Parameters[0].Value
+				So(parameter.Schema.Value, ShouldResemble, openapi3.NewStringSchema())
+			})
+
+			Convey("And it should be bound with UnmarshalText", func() {
+				request, _ := http.NewRequest("GET", "/paint?color=green", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				body, _ := io.ReadAll(response.Body)
+				So(string(body), ShouldEqual, `"green"`)
+			})
+		})
+
 		Convey("When setting up an operation with empty input or output", func() {
 			builder := engine.Get("/action", func(c *fiber.Ctx) error {
 				return nil
@@ -368,6 +1389,52 @@ func TestOperations(t *testing.T) {
 			So(string(body), ShouldEqual, string(expect))
 		})
 
+		Convey("Bind Duration", func() {
+			type durations struct {
+				Timeout  time.Duration `query:"timeout"`
+				Interval time.Duration `header:"interval"`
+				Delay    time.Duration `path:"delay"`
+			}
+			engine.Get("/duration/:delay", func(c *fiber.Ctx) error {
+				in := soda.GetInput[durations](c)
+				return c.SendString(in.Timeout.String() + " " + in.Interval.String() + " " + in.Delay.String())
+			}).SetInput(&durations{}).OK()
+
+			request, _ := http.NewRequest("GET", "/duration/2s?timeout=1h30m", nil)
+			request.Header.Add("interval", "500ms")
+			response, _ := engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			So(string(body), ShouldEqual, "1h30m0s 500ms 2s")
+
+			request, _ = http.NewRequest("GET", "/duration/2s?timeout=soon", nil)
+			response, _ = engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 500)
+		})
+
+		Convey("Bind database/sql nullable types", func() {
+			type nullables struct {
+				Name  sql.NullString `query:"name"`
+				Age   sql.NullInt64  `query:"age"`
+				Admin sql.NullBool   `header:"admin"`
+			}
+			engine.Get("/nullables", func(c *fiber.Ctx) error {
+				in := soda.GetInput[nullables](c)
+				return c.JSON(in)
+			}).SetInput(&nullables{}).OK()
+
+			request, _ := http.NewRequest("GET", "/nullables?name=soda", nil)
+			request.Header.Add("admin", "true")
+			response, _ := engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			expect, _ := json.Marshal(nullables{
+				Name:  sql.NullString{String: "soda", Valid: true},
+				Admin: sql.NullBool{Bool: true, Valid: true},
+			})
+			So(string(body), ShouldEqual, string(expect))
+		})
+
 		Convey("Bind Path", func() {
 			engine.Get("/test/:path", func(c *fiber.Ctx) error {
 				in := soda.GetInput[schema](c)
@@ -445,4 +1512,255 @@ func TestOperations(t *testing.T) {
 			So(string(body), ShouldEqual, string(expect))
 		})
 	})
+
+	Convey("When registering a custom type", t, func() {
+		soda.RegisterType(reflect.TypeOf(uuid.UUID{}), openapi3.NewUUIDSchema(), func(s string) (reflect.Value, error) {
+			id, err := uuid.Parse(s)
+			return reflect.ValueOf(id), err
+		})
+		type input struct {
+			ID uuid.UUID `path:"id"`
+		}
+		engine := soda.New()
+		engine.Get("/users/:id", func(c *fiber.Ctx) error {
+			return c.SendString(soda.GetInput[input](c).ID.String())
+		}).SetInput(&input{}).OK()
+
+		Convey("The parameter should be documented with the registered schema", func() {
+			parameter := engine.OpenAPI().Paths.Find("/users/:id").Get.Parameters.GetByInAndName("path", "id")
+			So(parameter.Schema.Value, ShouldResemble, openapi3.NewUUIDSchema())
+		})
+
+		Convey("The parameter should be bound with the registered converter", func() {
+			id := uuid.New()
+			request, _ := http.NewRequest("GET", "/users/"+id.String(), nil)
+			response, _ := engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			So(string(body), ShouldEqual, id.String())
+
+			request, _ = http.NewRequest("GET", "/users/not-a-uuid", nil)
+			response, _ = engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 500)
+		})
+
+		Convey("The parsers of the other fiber apps should be left as is", func() {
+			app := fiber.New()
+			app.Get("/users", func(c *fiber.Ctx) error {
+				var in struct {
+					Timeout time.Duration `query:"timeout"`
+				}
+				return c.QueryParser(&in)
+			})
+			request, _ := http.NewRequest("GET", "/users?timeout=1h", nil)
+			response, _ := app.Test(request)
+			So(response.StatusCode, ShouldEqual, 500)
+		})
+	})
+
+	Convey("When declaring content parameters", t, func() {
+		type filter struct {
+			Name string `json:"name"`
+			Age  int    `json:"age"`
+		}
+		type input struct {
+			Filter filter `query:"filter" oai:"content=application/json"`
+			Page   int    `query:"page"`
+		}
+		engine := soda.New()
+		engine.Get("/users", func(c *fiber.Ctx) error {
+			return c.JSON(soda.GetInput[input](c))
+		}).SetInput(&input{}).OK()
+
+		Convey("Then the parameter should be documented with a content map", func() {
+			parameter := engine.OpenAPI().Paths.Find("/users").Get.Parameters.GetByInAndName("query", "filter")
+			So(parameter.Schema, ShouldBeNil)
+			So(parameter.Content, ShouldContainKey, "application/json")
+			schema := parameter.Content["application/json"].Schema.Value
+			So(schema.Properties, ShouldContainKey, "name")
+			So(schema.Properties, ShouldContainKey, "age")
+		})
+
+		Convey("And the parameter should be decoded from JSON", func() {
+			request, _ := http.NewRequest("GET", "/users?page=2&filter="+url.QueryEscape(`{"name":"foo","age":1}`), nil)
+			response, _ := engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			expect, _ := json.Marshal(input{Filter: filter{Name: "foo", Age: 1}, Page: 2})
+			So(body, ShouldEqual, expect)
+
+			request, _ = http.NewRequest("GET", "/users?filter=not-json", nil)
+			response, _ = engine.App().Test(request)
+			So(response.StatusCode, ShouldEqual, 422)
+		})
+	})
+
+	Convey("When declaring the serialization of array parameters", t, func() {
+		type input struct {
+			Tags   []string `query:"tags" json:"tags,omitempty" oai:"explode=false"`
+			Terms  []string `query:"terms" json:"terms,omitempty" oai:"explode=true"`
+			Pipes  []string `query:"pipes" json:"pipes,omitempty" oai:"style=pipeDelimited"`
+			Header []int    `header:"X-Ids" json:"header,omitempty" oai:"style=simple;explode=false"`
+		}
+		engine := soda.NewWith(fiber.New(fiber.Config{EnableSplittingOnParsers: true}))
+		engine.Get("/search", func(c *fiber.Ctx) error {
+			return c.JSON(soda.GetInput[input](c))
+		}).SetInput(&input{}).OK()
+		search := func(query string, header string) string {
+			request, _ := http.NewRequest("GET", "/search?"+query, nil)
+			if header != "" {
+				request.Header.Set("X-Ids", header)
+			}
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			return string(body)
+		}
+
+		Convey("Then the non exploded form values should be split on commas", func() {
+			So(search("tags=a,b", "1,2"), ShouldEqual, `{"tags":["a","b"],"header":[1,2]}`)
+		})
+
+		Convey("And the exploded values should be taken from the repeated keys", func() {
+			So(search("terms=a&terms=b", ""), ShouldEqual, `{"terms":["a","b"]}`)
+			So(search("terms=a,b&terms=c", ""), ShouldEqual, `{"terms":["a,b","c"]}`)
+		})
+
+		Convey("And the other styles should not be split on commas", func() {
+			So(search("pipes=a,b", ""), ShouldEqual, `{"pipes":["a,b"]}`)
+		})
+	})
+
+	Convey("When declaring the style of path parameters", t, func() {
+		type input struct {
+			Coords []int    `path:"coords" json:"coords" oai:"style=matrix"`
+			Points []int    `path:"points" json:"points" oai:"style=matrix;explode=true"`
+			Labels []string `path:"labels" json:"labels" oai:"style=label"`
+			Dotted []string `path:"dotted" json:"dotted" oai:"style=label;explode=true"`
+			Name   string   `path:"name" json:"name" oai:"style=matrix"`
+		}
+		engine := soda.New()
+		engine.Get("/map/:coords/:points/:labels/:dotted/:name", func(c *fiber.Ctx) error {
+			return c.JSON(soda.GetInput[input](c))
+		}).SetInput(&input{}).OK()
+
+		Convey("Then the matrix and label encoded segments should be decoded", func() {
+			request, _ := http.NewRequest("GET", "/map/;coords=1,2/;points=3;points=4/.a,b/.c.d/;name=soda", nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			expect, _ := json.Marshal(input{
+				Coords: []int{1, 2},
+				Points: []int{3, 4},
+				Labels: []string{"a", "b"},
+				Dotted: []string{"c", "d"},
+				Name:   "soda",
+			})
+			So(string(body), ShouldEqual, string(expect))
+		})
+
+		Convey("And a style not allowed in the location should panic", func() {
+			type invalid struct {
+				Tags []string `query:"tags" oai:"style=matrix"`
+			}
+			So(func() { engine.Get("/invalid", nil).SetInput(&invalid{}) }, ShouldPanicWith,
+				"field Tags: style matrix is not allowed for query parameters")
+		})
+	})
+
+	Convey("When binding boolean query flags", t, func() {
+		type input struct {
+			Active   bool  `query:"active" json:"active"`
+			Archived *bool `query:"archived" json:"archived,omitempty"`
+		}
+		engine := soda.New()
+		engine.Get("/users", func(c *fiber.Ctx) error {
+			return c.JSON(soda.GetInput[input](c))
+		}).SetInput(&input{}).OK()
+		search := func(query string) string {
+			request, _ := http.NewRequest("GET", "/users?"+query, nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, 200)
+			body, _ := io.ReadAll(response.Body)
+			return string(body)
+		}
+
+		Convey("Then a flag without a value should be bound as true", func() {
+			So(search("active"), ShouldEqual, `{"active":true}`)
+			So(search("active&archived"), ShouldEqual, `{"active":true,"archived":true}`)
+		})
+
+		Convey("And a flag with a value should be bound by its value", func() {
+			So(search("active=false"), ShouldEqual, `{"active":false}`)
+			So(search("active=true&archived=false"), ShouldEqual, `{"active":true,"archived":false}`)
+		})
+
+		Convey("And a missing flag should be left unset", func() {
+			So(search(""), ShouldEqual, `{"active":false}`)
+		})
+	})
+
+	Convey("When the input validates itself", t, func() {
+		engine := soda.New()
+		engine.App().Use(func(c *fiber.Ctx) error {
+			c.SetUserContext(context.WithValue(c.UserContext(), quotaKey{}, 5))
+			return c.Next()
+		})
+		engine.Get("/range", func(c *fiber.Ctx) error {
+			return c.JSON(soda.GetInput[dateRange](c))
+		}).SetInput(&dateRange{}).OK()
+		engine.Get("/quota", func(c *fiber.Ctx) error {
+			return c.SendStatus(204)
+		}).SetInput(&quota{}).OK()
+		send := func(path string) *http.Response {
+			request, _ := http.NewRequest("GET", path, nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			return response
+		}
+
+		Convey("Then a valid input should be handled", func() {
+			So(send("/range?from=1&to=2").StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("And an invalid combination should be rejected with 400", func() {
+			response := send("/range?from=2&to=1")
+			So(response.StatusCode, ShouldEqual, 400)
+			body, _ := io.ReadAll(response.Body)
+			So(string(body), ShouldEqual, "from must not be after to")
+		})
+
+		Convey("And the context variant should be validated with the request context", func() {
+			So(send("/quota?count=5").StatusCode, ShouldEqual, 204)
+			So(send("/quota?count=6").StatusCode, ShouldEqual, 400)
+		})
+	})
+}
+
+type dateRange struct {
+	From int `query:"from" json:"from"`
+	To   int `query:"to" json:"to"`
+}
+
+func (r dateRange) Validate() error {
+	if r.From > r.To {
+		return errors.New("from must not be after to")
+	}
+	return nil
+}
+
+type quotaKey struct{}
+
+type quota struct {
+	Count int `query:"count"`
+}
+
+func (q *quota) Validate(ctx context.Context) error {
+	if limit, _ := ctx.Value(quotaKey{}).(int); q.Count > limit {
+		return fmt.Errorf("count exceeds the quota of %d", limit)
+	}
+	return nil
 }
@@ -0,0 +1,183 @@
+package soda
+
+import (
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Option is a function that configures the Generator.
+type Option func(*Generator)
+
+// WithDeprecatedOptional makes fields marked as deprecated optional,
+// so that clients are not forced to send them even if they are not pointers.
+func WithDeprecatedOptional() Option {
+	return func(g *Generator) {
+		g.deprecatedOptional = true
+	}
+}
+
+// WithPointersNullable marks the schema of pointer fields as nullable.
+// Referenced schemas are wrapped in a nullable allOf, and an explicit `oai:"nullable"` tag takes precedence.
+func WithPointersNullable() Option {
+	return func(g *Generator) {
+		g.pointersNullable = true
+	}
+}
+
+// WithCompactArrays preserves the nullability of the items of pointer slices, e.g. []*string,
+// which are otherwise collapsed into the schema of their element. Referenced items are wrapped in a nullable allOf.
+func WithCompactArrays() Option {
+	return func(g *Generator) {
+		g.compactArrays = true
+	}
+}
+
+// WithStrictObjects sets additionalProperties to false on the generated struct schemas, so that the clients reject
+// the unknown fields. Maps are unaffected, and a struct can opt out with _ struct{} `oai:"additionalProperties=true"`.
+func WithStrictObjects() Option {
+	return func(g *Generator) {
+		g.strictObjects = true
+	}
+}
+
+// WithExcludeUnexportedFields skips the unexported struct fields when generating the schemas, as encoding/json does.
+// The fields of the embedded structs are still promoted.
+func WithExcludeUnexportedFields() Option {
+	return func(g *Generator) {
+		g.excludeUnexported = true
+	}
+}
+
+// WithCleanOperationIDs generates the default operation ids without the leading, trailing and repeated dashes
+// and without the constraints of the path parameters, e.g. get-users-id for /users/:id<int> instead of get--users-id-int-.
+// It changes the ids of the existing operations, and thus the clients generated from the spec.
+func WithCleanOperationIDs() Option {
+	return func(g *Generator) {
+		g.cleanOperationIDs = true
+	}
+}
+
+// WithDurationAsInteger documents time.Duration as an integer of nanoseconds instead of a duration string.
+func WithDurationAsInteger() Option {
+	return func(g *Generator) {
+		g.durationAsInteger = true
+	}
+}
+
+// WithAutoRequestExample composes an example of the JSON request bodies from the `oai:"example"`
+// and `oai:"default"` values of their fields, so the UI shows a ready-to-send body.
+func WithAutoRequestExample() Option {
+	return func(g *Generator) {
+		g.autoRequestExample = true
+	}
+}
+
+// WithFormatValidation validates the string parameters of well-known formats at bind time,
+// such as email, uri, uuid, ipv4, ipv6, date and date-time, rejecting mismatches with a ValidationError.
+func WithFormatValidation() Option {
+	return func(g *Generator) {
+		g.validateFormats = true
+	}
+}
+
+// WithRequiredParameters rejects the requests missing a required query, header or cookie parameter with a ValidationError.
+// The pointers, the omitempty fields and the slices or maps tagged with `oai:"allowEmpty"` are optional.
+func WithRequiredParameters() Option {
+	return func(g *Generator) {
+		g.requireParameters = true
+	}
+}
+
+// WithValidateOnServe validates the spec when it is served, e.g. by Engine.ServeSpecJSON, and again after it changed,
+// responding with a 500 Internal Server Error and the validation error if it is invalid.
+// The info block is not validated, so that the engines created without WithInfo can be served.
+func WithValidateOnServe() Option {
+	return func(g *Generator) {
+		g.validateOnServe = true
+	}
+}
+
+// WithValidationErrorHandler sets the handler of the requests failing to bind or validate their input.
+// The errors of every location are collected into ValidationErrors named after the struct tags of the fields,
+// so the handler can respond with a machine-readable payload, e.g.:
+//
+//	soda.WithValidationErrorHandler(func(c *fiber.Ctx, errs []*soda.ValidationError) error {
+//		return c.Status(fiber.StatusBadRequest).JSON(errs)
+//	})
+func WithValidationErrorHandler(handler func(c *fiber.Ctx, errs []*ValidationError) error) Option {
+	return func(g *Generator) {
+		g.validationErrorHandler = handler
+	}
+}
+
+// WithErrorHandler sets a handler that receives errors raised while generating the schema of a struct field.
+// The failing field is skipped instead of panicking, letting the caller decide how to proceed.
+func WithErrorHandler(handler func(error)) Option {
+	return func(g *Generator) {
+		g.errorHandler = handler
+	}
+}
+
+// WithSchemaNamer sets a custom naming strategy for the component schemas generated from Go types,
+// e.g. to strip the package prefix of the default names. Colliding names panic.
+func WithSchemaNamer(namer func(reflect.Type) string) Option {
+	return func(g *Generator) {
+		g.schemaNamer = namer
+	}
+}
+
+// WithComponentPrefix prefixes the names of all the component schemas, and their refs,
+// so that the specs of several engines can be merged without collisions.
+// The names given to NewDiscriminator are not prefixed and must include the prefix.
+func WithComponentPrefix(prefix string) Option {
+	return func(g *Generator) {
+		g.componentPrefix = prefix
+	}
+}
+
+// WithResponseEnvelope wraps every declared JSON response schema in the given envelope model,
+// replacing the envelope property named dataField (by its json name) with the response schema.
+func WithResponseEnvelope(envelope any, dataField string) Option {
+	return func(g *Generator) {
+		g.envelopeType = reflect.TypeOf(envelope)
+		g.envelopeField = dataField
+	}
+}
+
+// WithInfo sets the title and version of the API documented in the spec.
+func WithInfo(title, version string) Option {
+	return func(g *Generator) {
+		g.doc.Info.Title = title
+		g.doc.Info.Version = version
+	}
+}
+
+// WithDescription sets the description of the API documented in the spec.
+func WithDescription(description string) Option {
+	return func(g *Generator) {
+		g.doc.Info.Description = description
+	}
+}
+
+// WithContact sets the contact information of the API documented in the spec.
+func WithContact(name, url, email string) Option {
+	return func(g *Generator) {
+		g.doc.Info.Contact = &openapi3.Contact{Name: name, URL: url, Email: email}
+	}
+}
+
+// WithLicense sets the license of the API documented in the spec.
+func WithLicense(name, url string) Option {
+	return func(g *Generator) {
+		g.doc.Info.License = &openapi3.License{Name: name, URL: url}
+	}
+}
+
+// WithTermsOfService sets the URL of the terms of service of the API documented in the spec.
+func WithTermsOfService(url string) Option {
+	return func(g *Generator) {
+		g.doc.Info.TermsOfService = url
+	}
+}
@@ -0,0 +1,49 @@
+package soda
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MIMEApplicationProblemJSON is the media type of the RFC 7807 problem details.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ProblemSchemaName is the name of the component schema documenting the problem details.
+const ProblemSchemaName = "Problem"
+
+// Problem is an RFC 7807 problem details object, describing an error in a machine-readable way.
+type Problem struct {
+	Type     string `json:"type,omitempty"     oai:"format=uri-reference;default=about:blank;description=A URI reference identifying the problem type"`
+	Title    string `json:"title,omitempty"    oai:"description=A short summary of the problem type"`
+	Status   int    `json:"status,omitempty"   oai:"minimum=100;maximum=599;description=The HTTP status code"`
+	Detail   string `json:"detail,omitempty"   oai:"description=An explanation specific to this occurrence of the problem"`
+	Instance string `json:"instance,omitempty" oai:"format=uri-reference;description=A URI reference identifying this occurrence of the problem"`
+}
+
+// SendProblem responds with the problem details, its status defaults to 500 Internal Server Error
+// and its title to the text of the status.
+func SendProblem(c *fiber.Ctx, problem Problem) error {
+	if problem.Status == 0 {
+		problem.Status = fiber.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	return c.Status(problem.Status).JSON(problem, MIMEApplicationProblemJSON)
+}
+
+// generateProblemResponse generates a problem details response, referencing the Problem component schema.
+// Unlike the other JSON responses, it is never wrapped in the response envelope.
+func (g *Generator) generateProblemResponse(code int, description string) *openapi3.Response {
+	desc := http.StatusText(code)
+	if description != "" {
+		desc = description
+	}
+	schema := g.generateSchemaRef(nil, reflect.TypeOf(Problem{}), "json", ProblemSchemaName)
+	return openapi3.NewResponse().
+		WithDescription(desc).
+		WithContent(openapi3.NewContentWithSchemaRef(schema, []string{MIMEApplicationProblemJSON}))
+}
@@ -30,7 +30,7 @@ func (r *Router) createOperationBuilder(method string, pattern, patternFull stri
 		route: r,
 		operation: &openapi3.Operation{
 			Summary:     method + " " + patternFull,
-			OperationID: genDefaultOperationID(method, patternFull),
+			OperationID: genDefaultOperationID(method, patternFull, r.gen.cleanOperationIDs),
 			Security:    &r.commonSecurities,
 		},
 		method:      method,
@@ -103,11 +103,12 @@ func (r *Router) SetDeprecated(deprecated bool) *Router {
 	return r
 }
 
-func (r *Router) AddSecurity(securityName string, scheme *openapi3.SecurityScheme) *Router {
+// AddSecurity adds a security scheme to the operations of the router, optionally requiring the given scopes.
+func (r *Router) AddSecurity(securityName string, scheme *openapi3.SecurityScheme, scopes ...string) *Router {
 	r.gen.doc.Components.SecuritySchemes[securityName] = &openapi3.SecuritySchemeRef{Value: scheme}
 	r.commonSecurities = append(
 		r.commonSecurities,
-		openapi3.SecurityRequirement{securityName: nil},
+		openapi3.SecurityRequirement{securityName: scopes},
 	)
 	return r
 }
@@ -146,6 +147,19 @@ func (r *Router) AddJSONResponse(code int, model any, description ...string) *Ro
 	return r
 }
 
+// AddProblemResponse adds an RFC 7807 application/problem+json error response to all the operations of the router.
+func (r *Router) AddProblemResponse(code int, description ...string) *Router {
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	if r.commonResponses == nil {
+		r.commonResponses = make(map[int]*openapi3.Response)
+	}
+	r.commonResponses[code] = r.gen.generateProblemResponse(code, desc)
+	return r
+}
+
 func (r *Router) Group(prefix string, handlers ...fiber.Handler) *Router {
 	return &Router{
 		gen:                   r.gen,
@@ -60,6 +60,17 @@ func TestRouter(t *testing.T) {
 			})
 		})
 
+		Convey("When adding a security scheme requiring scopes", func() {
+			flows := soda.NewOAuth2AuthorizationCodeFlows("https://auth.example.com/authorize", "https://auth.example.com/token", nil)
+			engine.AddSecurity("oauth2", soda.NewOAuth2SecurityScheme(flows), "admin")
+			engine.Get("/admin", handler).OK()
+
+			Convey("The scopes should be required by the operations", func() {
+				security := *engine.OpenAPI().Paths.Find("/admin").Get.Security
+				So(security, ShouldResemble, openapi3.SecurityRequirements{{"oauth2": []string{"admin"}}})
+			})
+		})
+
 		Convey("When adding a JSON response", func() {
 			engine.AddJSONResponse(http.StatusOK, map[string]string{"message": "ok"})
 			engine.Get("/json", handler).OK()
@@ -117,6 +128,48 @@ func TestRouter(t *testing.T) {
 			})
 		})
 
+		Convey("When mounting the same routes under multiple prefixes", func() {
+			mount := func(r *soda.Router) {
+				r.Get("/users", handler).SetOperationID("list-users").OK()
+			}
+			mount(engine.Group("/v1"))
+			mount(engine.Group("/v2"))
+			mount(engine.Group("/v3"))
+
+			Convey("The operation IDs should be unique", func() {
+				So(engine.OpenAPI().Paths.Find("/v1/users").Get.OperationID, ShouldEqual, "list-users")
+				So(engine.OpenAPI().Paths.Find("/v2/users").Get.OperationID, ShouldEqual, "list-users-2")
+				So(engine.OpenAPI().Paths.Find("/v3/users").Get.OperationID, ShouldEqual, "list-users-3")
+			})
+		})
+
+		Convey("When registering routes in nested groups", func() {
+			operationIDs := func(engine *soda.Engine) []string {
+				users := engine.Group("/api/").Group("/v1").Group("users")
+				users.Get("/", handler).OK()
+				users.Get("/:id<int>", handler).OK()
+				users.Delete("/:id/sessions/:session?", handler).OK()
+				var ids []string
+				engine.WalkOperations(func(method, path string, op *openapi3.Operation) {
+					ids = append(ids, op.OperationID)
+				})
+				return ids
+			}
+
+			Convey("The default operation ids should keep their format", func() {
+				ids := operationIDs(engine)
+				So(ids, ShouldContain, "get--api-v1-users")
+				So(ids, ShouldContain, "get--api-v1-users-id-int-")
+			})
+
+			Convey("The default operation ids should be clean with WithCleanOperationIDs", func() {
+				ids := operationIDs(soda.New(soda.WithCleanOperationIDs()))
+				So(ids, ShouldContain, "get-api-v1-users")
+				So(ids, ShouldContain, "get-api-v1-users-id")
+				So(ids, ShouldContain, "delete-api-v1-users-id-sessions-session")
+			})
+		})
+
 		Convey("When creating a group", func() {
 			group := engine.Group("/api")
 
@@ -196,4 +249,38 @@ func TestRouter(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given an engine with a response envelope", t, func() {
+		type Envelope struct {
+			Data  any     `json:"data"`
+			Error *string `json:"error"`
+		}
+		type User struct {
+			Name string `json:"name"`
+		}
+		engine := soda.New(soda.WithResponseEnvelope(Envelope{}, "data"))
+		engine.AddJSONResponse(400, nil)
+		engine.Get("/user", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, User{}).OK()
+
+		Convey("The response schema should be wrapped in the envelope", func() {
+			operation := engine.OpenAPI().Paths.Find("/user").Get
+			schema := operation.Responses.Status(200).Value.Content.Get("application/json").Schema.Value
+			So(schema.Properties, ShouldContainKey, "error")
+			So(schema.Properties["data"].Ref, ShouldEqual, "#/components/schemas/soda_test.User")
+			So(schema.Required, ShouldResemble, []string{"data"})
+		})
+
+		Convey("Problem responses should not be wrapped", func() {
+			engine.AddProblemResponse(500)
+			engine.Get("/problem", func(c *fiber.Ctx) error { return nil }).OK()
+			operation := engine.OpenAPI().Paths.Find("/problem").Get
+			schema := operation.Responses.Status(500).Value.Content.Get("application/problem+json").Schema
+			So(schema.Ref, ShouldEqual, "#/components/schemas/Problem")
+		})
+
+		Convey("Responses without a model should not be wrapped", func() {
+			operation := engine.OpenAPI().Paths.Find("/user").Get
+			So(operation.Responses.Status(400).Value.Content, ShouldBeNil)
+		})
+	})
 }
@@ -1,27 +1,69 @@
 package soda
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
+	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"math"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"path"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
 )
 
 // Define some well-known types.
 var (
-	wnTime         = reflect.TypeOf(time.Time{})       // date-time RFC section 8.3.1
-	wnIP           = reflect.TypeOf(net.IP{})          // ipv4 and ipv6 RFC section 7.3.4, 7.3.5
-	wnByteSlice    = reflect.TypeOf([]byte(nil))       // Byte slices will be encoded as base64
-	wnJSON         = reflect.TypeOf(json.RawMessage{}) // Except for json.RawMessage
-	wnMapStringAny = reflect.TypeOf(map[string]any{})  // Except for map[string]any
+	wnTime         = reflect.TypeOf(time.Time{})            // date-time RFC section 8.3.1
+	wnDuration     = reflect.TypeOf(time.Duration(0))       // duration RFC section 7.3.1, e.g. 1h30m
+	wnIP           = reflect.TypeOf(net.IP{})               // ipv4 and ipv6 RFC section 7.3.4, 7.3.5
+	wnByteSlice    = reflect.TypeOf([]byte(nil))            // Byte slices will be encoded as base64
+	wnJSON         = reflect.TypeOf(json.RawMessage{})      // Except for json.RawMessage
+	wnMapStringAny = reflect.TypeOf(map[string]any{})       // Except for map[string]any
+	wnFile         = reflect.TypeOf(multipart.FileHeader{}) // Uploaded files of multipart bodies
 )
 
+// Define the nullable types of database/sql, documented as their underlying nullable scalar.
+var sqlNullSchemaFunc = map[reflect.Type]func() *openapi3.Schema{
+	reflect.TypeOf(sql.NullString{}):  openapi3.NewStringSchema,
+	reflect.TypeOf(sql.NullInt64{}):   primitiveSchemaFunc[reflect.Int64],
+	reflect.TypeOf(sql.NullInt32{}):   primitiveSchemaFunc[reflect.Int32],
+	reflect.TypeOf(sql.NullInt16{}):   primitiveSchemaFunc[reflect.Int16],
+	reflect.TypeOf(sql.NullByte{}):    primitiveSchemaFunc[reflect.Uint8],
+	reflect.TypeOf(sql.NullFloat64{}): openapi3.NewFloat64Schema,
+	reflect.TypeOf(sql.NullBool{}):    openapi3.NewBoolSchema,
+	reflect.TypeOf(sql.NullTime{}):    openapi3.NewDateTimeSchema,
+}
+
+// customTypes are the schemas of the types registered with RegisterType.
+var customTypes = map[reflect.Type]*openapi3.Schema{}
+
+// examples are the example values of the types registered with RegisterExample.
+var examples = map[reflect.Type]any{}
+
+// RegisterExample registers an example value of a type, attached as the example of the schemas generated for it.
+// It keeps the examples close to the code, e.g. next to the type or in an ExampleXxx function:
+//
+//	soda.RegisterExample(reflect.TypeOf(User{}), User{Name: "soda"})
+//
+// It is not safe for concurrent use and should be called before the routes are registered.
+func RegisterExample(typ reflect.Type, value any) {
+	examples[typ] = value
+}
+
 // Define an interface for JSON schema generation.
 type jsonSchema interface {
 	JSONSchema(*openapi3.T) *openapi3.SchemaRef
@@ -30,14 +72,121 @@ type jsonSchema interface {
 // Get the type of the jsonSchema interface.
 var jsonSchemaFunc = reflect.TypeOf((*jsonSchema)(nil)).Elem()
 
+// Get the types of the marshaling interfaces, the text marshalers are encoded as JSON strings unless they are JSON marshalers.
+var (
+	textMarshalerFunc = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerFunc = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// OneOfSchema is implemented by polymorphic types documented as exactly one of the returned schemas.
+type OneOfSchema interface {
+	OneOf(*openapi3.T) []*openapi3.SchemaRef
+}
+
+// AnyOfSchema is implemented by types documented as any of the returned schemas.
+type AnyOfSchema interface {
+	AnyOf(*openapi3.T) []*openapi3.SchemaRef
+}
+
+// AllOfSchema is implemented by types documented as all of the returned schemas.
+type AllOfSchema interface {
+	AllOf(*openapi3.T) []*openapi3.SchemaRef
+}
+
+// DiscriminatorSchema can be implemented along with OneOfSchema or AnyOfSchema
+// to set the discriminator of the composed schema.
+type DiscriminatorSchema interface {
+	Discriminator() *openapi3.Discriminator
+}
+
+// NamedExamples is implemented by models providing named examples of their payloads,
+// documented as the examples of the JSON request bodies and responses of the model.
+type NamedExamples interface {
+	Examples() map[string]any
+}
+
+// Get the type of the NamedExamples interface.
+var namedExamplesFunc = reflect.TypeOf((*NamedExamples)(nil)).Elem()
+
+// namedExamples returns the named examples of the model type, or nil if it does not implement NamedExamples.
+// The examples are converted to their JSON representation, so that they are validated against the schema.
+func namedExamples(t reflect.Type) openapi3.Examples {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !reflect.PointerTo(t).Implements(namedExamplesFunc) {
+		return nil
+	}
+	values := reflect.New(t).Interface().(NamedExamples).Examples()
+	if len(values) == 0 {
+		return nil
+	}
+	examples := make(openapi3.Examples, len(values))
+	for name, value := range values {
+		examples[name] = &openapi3.ExampleRef{Value: openapi3.NewExample(toJSONValue(value))}
+	}
+	return examples
+}
+
+// NewDiscriminator creates a discriminator for the given property name.
+// The mapping values may be bare component names, they are expanded to component references.
+func NewDiscriminator(propertyName string, mapping map[string]string) *openapi3.Discriminator {
+	discriminator := &openapi3.Discriminator{PropertyName: propertyName}
+	if len(mapping) > 0 {
+		discriminator.Mapping = make(map[string]string, len(mapping))
+		for value, name := range mapping {
+			if !strings.HasPrefix(name, "#") {
+				name = "#/components/schemas/" + name
+			}
+			discriminator.Mapping[value] = name
+		}
+	}
+	return discriminator
+}
+
 // Generator Define the Generator struct.
 type Generator struct {
 	doc *openapi3.T
+
+	deprecatedOptional bool
+	pointersNullable   bool
+	compactArrays      bool
+	strictObjects      bool
+	excludeUnexported  bool
+	cleanOperationIDs  bool
+	durationAsInteger  bool
+	autoRequestExample bool
+	validateFormats    bool
+	requireParameters  bool
+	validateOnServe    bool
+	errorHandler       func(error)
+
+	validationErrorHandler func(*fiber.Ctx, []*ValidationError) error
+
+	operationIDs map[string]int
+
+	// version is bumped whenever an operation is added or removed, to invalidate the served specs.
+	version atomic.Uint64
+
+	envelopeType  reflect.Type
+	envelopeField string
+
+	schemaOfTypes map[string]reflect.Type
+
+	schemaNamer func(reflect.Type) string
+	schemaTypes map[string]reflect.Type
+	// schemaVariants maps the names of the request and response variants to their component.
+	schemaVariants  map[string]*schemaVariant
+	typeNames       map[reflect.Type]string
+	componentPrefix string
+
+	// defaultResponses maps the status codes to the name of the response component attached by default.
+	defaultResponses map[int]string
 }
 
 // NewGenerator Create a new generator.
-func NewGenerator() *Generator {
-	return &Generator{
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{
 		doc: &openapi3.T{
 			OpenAPI: "3.0.3",
 			Paths:   openapi3.NewPaths(),
@@ -54,7 +203,34 @@ func NewGenerator() *Generator {
 			},
 			Info: &openapi3.Info{},
 		},
+		operationIDs:   make(map[string]int),
+		schemaOfTypes:  make(map[string]reflect.Type),
+		schemaTypes:    make(map[string]reflect.Type),
+		schemaVariants: make(map[string]*schemaVariant),
+		typeNames:      make(map[reflect.Type]string),
+
+		defaultResponses: make(map[int]string),
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
+}
+
+// cloneOptions returns a generator with an empty document and the schema options of g,
+// so that it generates the schemas as they are in the document of g.
+func (g *Generator) cloneOptions() *Generator {
+	clone := NewGenerator()
+	clone.deprecatedOptional = g.deprecatedOptional
+	clone.pointersNullable = g.pointersNullable
+	clone.compactArrays = g.compactArrays
+	clone.strictObjects = g.strictObjects
+	clone.excludeUnexported = g.excludeUnexported
+	clone.durationAsInteger = g.durationAsInteger
+	clone.schemaNamer = g.schemaNamer
+	clone.typeNames = maps.Clone(g.typeNames)
+	clone.componentPrefix = g.componentPrefix
+	return clone
 }
 
 // Generate TestCase for a given type.
@@ -73,18 +249,34 @@ func (g *Generator) generateParameters(parameters *openapi3.Parameters, t reflec
 			continue
 		}
 
+		// the unexported fields cannot be bound
+		if !f.IsExported() {
+			continue
+		}
+
 		in := g.determineParameterLocation(f)
 		if in == "" {
 			continue
 		}
 
-		fieldSchemaRef := g.generateSchemaRef(nil, f.Type, in)
 		field := newTagsResolver(f)
+		mediaType, hasContent := field.pairs[propContent]
+		nameTag := in
+		if hasContent {
+			// the content parameters are serialized as a whole, their properties are named as in the body
+			nameTag = "json"
+		}
+		fieldSchemaRef := g.generateSchemaRef(nil, f.Type, nameTag)
 		schema := derefSchema(g.doc, fieldSchemaRef)
 		field.injectOAITags(schema)
 
 		parameter := g.createParameter(field, schema, in, fieldSchemaRef)
 		g.setAdditionalProperties(&parameter, field)
+		if hasContent {
+			mediaType, _ = resolveMediaType(mediaType)
+			parameter.Content = openapi3.NewContentWithSchemaRef(fieldSchemaRef, []string{mediaType})
+			parameter.Schema = nil
+		}
 		*parameters = append(*parameters, &openapi3.ParameterRef{Value: &parameter})
 	}
 }
@@ -102,22 +294,106 @@ func (g *Generator) createParameter(field *tagsResolver, schema *openapi3.Schema
 	return openapi3.Parameter{
 		In:          in,
 		Name:        field.name(in),
-		Required:    field.required() || in == "path", // path parameters are always required
+		Required:    g.required(field) || in == "path", // path parameters are always required
 		Description: schema.Description,
 		Deprecated:  schema.Deprecated,
 		Schema:      schemaRef,
 	}
 }
 
+// required checks if the field is required, taking the generator options into account.
+// An explicit `oai:"required"` tag still wins over WithDeprecatedOptional.
+func (g *Generator) required(field *tagsResolver) bool {
+	if _, ok := field.explicitRequired(); !ok && g.deprecatedOptional && field.deprecated() {
+		return false
+	}
+	return field.required()
+}
+
 func (g *Generator) setAdditionalProperties(parameter *openapi3.Parameter, field *tagsResolver) {
 	if v, ok := field.pairs[propExplode]; ok {
 		parameter.Explode = ptr(toBool(v))
 	}
 	if v, ok := field.pairs[propStyle]; ok {
+		if !slices.Contains(parameterStyles[parameter.In], v) {
+			panic(fmt.Sprintf("field %s: style %s is not allowed for %s parameters", field.f.Name, v, parameter.In))
+		}
 		parameter.Style = v
 	}
 }
 
+// parameterStyles lists the serialization styles allowed in each parameter location.
+var parameterStyles = map[string][]string{
+	PathTag:   {openapi3.SerializationSimple, openapi3.SerializationLabel, openapi3.SerializationMatrix},
+	QueryTag:  {openapi3.SerializationForm, openapi3.SerializationSpaceDelimited, openapi3.SerializationPipeDelimited, openapi3.SerializationDeepObject},
+	HeaderTag: {openapi3.SerializationSimple},
+	CookieTag: {openapi3.SerializationForm},
+}
+
+// uniqueOperationID returns the given operation ID, suffixed with a counter if it is already used in the document.
+// This happens when the same routes are mounted under multiple prefixes with explicit operation IDs.
+func (g *Generator) uniqueOperationID(id string) string {
+	g.operationIDs[id]++
+	if n := g.operationIDs[id]; n > 1 {
+		return g.uniqueOperationID(id + "-" + strconv.Itoa(n))
+	}
+	return id
+}
+
+// addDefaultResponses references the default responses from the operation, for the status codes it does not define.
+func (g *Generator) addDefaultResponses(operation *openapi3.Operation) {
+	codes := make([]int, 0, len(g.defaultResponses))
+	for code := range g.defaultResponses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		if operation.Responses.Status(code) != nil {
+			continue
+		}
+		if operation.Responses == nil {
+			operation.Responses = openapi3.NewResponses()
+		}
+		operation.Responses.Set(strconv.Itoa(code), g.responseRef(g.defaultResponses[code]))
+	}
+}
+
+// responseRef returns a reference to the response component with the given name, or nil if it is not registered.
+func (g *Generator) responseRef(name string) *openapi3.ResponseRef {
+	response, ok := g.doc.Components.Responses[name]
+	if !ok {
+		return nil
+	}
+	return &openapi3.ResponseRef{Ref: "#/components/responses/" + name, Value: response.Value}
+}
+
+// sortTags orders the tags by the lowest display order of their operations, the tags without one come last.
+func (g *Generator) sortTags() {
+	orders := make(map[string]int)
+	for _, item := range g.doc.Paths.Map() {
+		for _, operation := range item.Operations() {
+			order, ok := operation.Extensions[extDisplayOrder].(int)
+			if !ok {
+				continue
+			}
+			for _, tag := range operation.Tags {
+				if current, seen := orders[tag]; !seen || order < current {
+					orders[tag] = order
+				}
+			}
+		}
+	}
+	rank := func(tag *openapi3.Tag) int {
+		if order, ok := orders[tag.Name]; ok {
+			return order
+		}
+		return math.MaxInt
+	}
+	slices.SortStableFunc(g.doc.Tags, func(a, b *openapi3.Tag) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+}
+
 // GenerateParameters generates OpenAPI TestCase for a given model.
 func (g *Generator) GenerateParameters(model reflect.Type) openapi3.Parameters {
 	parameters := make(openapi3.Parameters, 0)
@@ -134,13 +410,60 @@ func (g *Generator) GenerateParameters(model reflect.Type) openapi3.Parameters {
 // It returns a *spec.RequestBody that represents the generated request body.
 func (g *Generator) GenerateRequestBody(operationID, nameTag string, model reflect.Type) *openapi3.RequestBody {
 	schema := g.generateSchemaRef(nil, model, nameTag, operationID+"-body")
-	return openapi3.
+	// the read-only properties are not sent by the clients, the body component belongs to the operation
+	if value := g.resolveSchema(schema); value != nil && g.hasDropped(schema, isReadOnly, nil) {
+		g.dropProperties(value, "Request", isReadOnly)
+	}
+	requestBody := openapi3.
 		NewRequestBody().
 		WithRequired(true).
 		WithJSONSchemaRef(schema)
+	mediaType := requestBody.Content.Get("application/json")
+	// the named examples take precedence over the composed one, as they are mutually exclusive
+	if examples := namedExamples(model); examples != nil {
+		mediaType.Examples = examples
+	} else if g.autoRequestExample {
+		if example := g.composeExample(schema, nil); example != nil {
+			mediaType.Example = example
+		}
+	}
+	return requestBody
+}
+
+// composeExample composes an example value from the example or default values of the schema,
+// recursing into the properties of objects. It returns nil when there is nothing to show.
+func (g *Generator) composeExample(ref *openapi3.SchemaRef, visited []*openapi3.Schema) any {
+	schema := derefSchema(g.doc, ref)
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if !schema.Type.Is(typeObject) || len(schema.Properties) == 0 || slices.Contains(visited, schema) {
+		return nil
+	}
+	visited = append(visited, schema)
+
+	example := make(map[string]any)
+	for name, property := range schema.Properties {
+		if value := g.composeExample(property, visited); value != nil {
+			example[name] = value
+		}
+	}
+	if len(example) == 0 {
+		return nil
+	}
+	return example
 }
 
 func (g *Generator) GenerateResponse(code int, model any, mt string, description string) *openapi3.Response {
+	return g.generateResponse(code, model, mt, description)
+}
+
+// generateResponse generates an OpenAPI response for a given model,
+// registering its schema under the given name if one is provided.
+func (g *Generator) generateResponse(code int, model any, mt string, description string, name ...string) *openapi3.Response {
 	desc := http.StatusText(code)
 	if description != "" {
 		desc = description
@@ -150,13 +473,59 @@ func (g *Generator) GenerateResponse(code int, model any, mt string, description
 		return response
 	}
 
-	if mt == "application/json" {
-		schema := g.generateSchemaRef(nil, reflect.TypeOf(model), "json")
-		return response.WithJSONSchemaRef(schema)
+	mt, _ = resolveMediaType(mt)
+	if isJSONMediaType(mt) {
+		schema := g.generateSchemaRef(nil, reflect.TypeOf(model), "json", name...)
+		// the write-only properties are not sent to the clients
+		schema = g.readWriteVariant(schema, "Response", isWriteOnly)
+		content := openapi3.NewContentWithSchemaRef(g.envelop(schema), []string{mt})
+		// the named examples document the model, they would not match an enveloped response
+		if g.envelopeType == nil {
+			content[mt].Examples = namedExamples(reflect.TypeOf(model))
+		}
+		return response.WithContent(content)
+	}
+	if mt == mediaTypeNDJSON {
+		// newline delimited JSON streams are documented with the schema of their items
+		t := reflect.TypeOf(model)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		schema := g.generateSchemaRef(nil, t, "json", name...)
+		return response.WithContent(openapi3.NewContentWithSchemaRef(schema, []string{mt}))
+	}
+	if mt == fiber.MIMEApplicationXML || strings.HasSuffix(mt, "+xml") {
+		schema := g.generateSchemaRef(nil, reflect.TypeOf(model), "xml", name...)
+		return response.WithContent(openapi3.NewContentWithSchemaRef(schema, []string{mt}))
+	}
+	if strings.HasPrefix(mt, "text/") {
+		return response.WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{mt}))
+	}
+	if mt == "*/*" || mt == fiber.MIMEOctetStream {
+		// arbitrary content types are documented as raw binary
+		schema := openapi3.NewStringSchema().WithFormat(formatBinary)
+		return response.WithContent(openapi3.NewContentWithSchema(schema, []string{mt}))
 	}
 	panic("unsupported media type " + mt)
 }
 
+// envelop wraps the given response schema in the configured envelope, replacing the envelope's data field.
+// It returns the schema as is if no envelope is configured.
+func (g *Generator) envelop(data *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if g.envelopeType == nil {
+		return data
+	}
+	envelope := derefSchema(g.doc, g.generateSchemaRef(nil, g.envelopeType, "json"))
+	schema := openapi3.NewObjectSchema()
+	maps.Copy(schema.Properties, envelope.Properties)
+	schema.Properties[g.envelopeField] = data
+	schema.Required = slices.Clone(envelope.Required)
+	return schema.NewRef()
+}
+
 var primitiveSchemaFunc = map[reflect.Kind]func() *openapi3.Schema{
 	reflect.Int: openapi3.NewIntegerSchema,
 	reflect.Uint: func() *openapi3.Schema {
@@ -193,20 +562,67 @@ var primitiveSchemaFunc = map[reflect.Kind]func() *openapi3.Schema{
 	reflect.Interface: openapi3.NewSchema,
 }
 
+// composedSchema returns the oneOf/anyOf/allOf schema of the types implementing the composition interfaces,
+// registering the referenced sub-schemas in the components. It returns nil for any other type.
+func (g *Generator) composedSchema(t reflect.Type) *openapi3.Schema {
+	v := reflect.New(t).Interface()
+	schema := openapi3.NewSchema()
+	switch c := v.(type) {
+	case OneOfSchema:
+		schema.OneOf = g.registerSubSchemas(c.OneOf(g.doc))
+	case AnyOfSchema:
+		schema.AnyOf = g.registerSubSchemas(c.AnyOf(g.doc))
+	case AllOfSchema:
+		schema.AllOf = g.registerSubSchemas(c.AllOf(g.doc))
+	default:
+		return nil
+	}
+	if d, ok := v.(DiscriminatorSchema); ok {
+		schema.Discriminator = d.Discriminator()
+	}
+	return schema
+}
+
+// registerSubSchemas adds the values of the component references to the components
+// when they are not defined yet.
+func (g *Generator) registerSubSchemas(refs []*openapi3.SchemaRef) openapi3.SchemaRefs {
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref.Ref, "#/components/schemas/")
+		if !ok || ref.Value == nil {
+			continue
+		}
+		if _, exists := g.doc.Components.Schemas[name]; !exists {
+			g.doc.Components.Schemas[name] = ref.Value.NewRef()
+		}
+	}
+	return refs
+}
+
 // generateSchemaRef generates an OpenAPI schema for a given type.
 // It takes in a slice of parent types to check for circular references,
 // the type to generate a schema for, a name tag to use for naming properties,
 // and an optional name for the schema.
 // It returns a RefOrSpec[Schema] that can be used to reference the generated schema.
-func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, nameTag string, name ...string) *openapi3.SchemaRef { //nolint
+func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, nameTag string, name ...string) (ref *openapi3.SchemaRef) { //nolint
 	// Remove any pointer types from the type.
 	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-	// Check for circular references.
+	// Attach the registered example to the generated schema.
+	if example, ok := examples[t]; ok {
+		defer func() {
+			if ref != nil && ref.Value != nil {
+				ref.Value.Example = example
+			}
+		}()
+	}
+	// Check for circular references, the instantiations of a generic type being distinct types.
+	if len(parents) >= maxSchemaDepth {
+		panic(fmt.Sprintf("schema of %s is nested deeper than %d levels", t, maxSchemaDepth))
+	}
 	for _, parent := range parents {
 		if parent == t {
-			schemaName := g.generateSchemaName(t, name...)
+			schemaName := g.componentName(t, nameTag, name...)
 			return openapi3.NewSchemaRef("#/components/schemas/"+schemaName, nil)
 		}
 	}
@@ -215,15 +631,35 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 		js := reflect.New(t).Interface().(jsonSchema).JSONSchema(g.doc)
 		return js
 	}
+	// Check if the type is composed of other schemas.
+	if composed := g.composedSchema(t); composed != nil {
+		return composed.NewRef()
+	}
 	parents = append(parents, t)
 
-	// Handle primitive types.
-	if primitiveSchema, ok := primitiveSchemaFunc[t.Kind()]; ok {
-		return primitiveSchema().NewRef()
+	// Handle the registered custom types.
+	typesMu.RLock()
+	custom, ok := customTypes[t]
+	typesMu.RUnlock()
+	if schema := custom; ok {
+		schema := *schema
+		return schema.NewRef()
+	}
+
+	// Handle the nullable types of database/sql.
+	if sqlNullSchema, ok := sqlNullSchemaFunc[t]; ok {
+		return sqlNullSchema().WithNullable().NewRef()
 	}
 
 	// Handle well-known types.
 	switch t {
+	case wnDuration:
+		if g.durationAsInteger {
+			schema := openapi3.NewInt64Schema()
+			schema.Description = "duration in nanoseconds"
+			return schema.NewRef()
+		}
+		return openapi3.NewStringSchema().WithFormat("duration").NewRef()
 	case wnMapStringAny:
 		return openapi3.NewObjectSchema().WithAnyAdditionalProperties().NewRef()
 	case wnTime:
@@ -234,6 +670,18 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 		return openapi3.NewBytesSchema().NewRef()
 	case wnJSON:
 		return openapi3.NewStringSchema().WithFormat("json").NewRef()
+	case wnFile:
+		return openapi3.NewStringSchema().WithFormat(formatBinary).NewRef()
+	}
+
+	// Handle the types encoded as text, such as custom enums or netip.Addr.
+	if pt := reflect.PointerTo(t); pt.Implements(textMarshalerFunc) && !pt.Implements(jsonMarshalerFunc) {
+		return openapi3.NewStringSchema().NewRef()
+	}
+
+	// Handle primitive types.
+	if primitiveSchema, ok := primitiveSchemaFunc[t.Kind()]; ok {
+		return primitiveSchema().NewRef()
 	}
 
 	// Handle arrays and slices.
@@ -244,6 +692,9 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 			schema.MaxItems = ptr(schema.MinItems)
 		}
 		schema.Items = g.generateSchemaRef(parents, t.Elem(), nameTag)
+		if g.compactArrays && t.Elem().Kind() == reflect.Ptr {
+			schema.Items = nullableItems(schema.Items)
+		}
 		return schema.NewRef()
 	}
 	// Handle maps.
@@ -254,7 +705,16 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 
 	// Handle structs.
 	if t.Kind() == reflect.Struct {
+		// Reuse the component already generated for the type.
+		schemaName := g.componentName(t, nameTag, name...)
+		if existing, ok := g.doc.Components.Schemas[schemaName]; ok && g.schemaTypes[schemaName] == t {
+			return openapi3.NewSchemaRef("#/components/schemas/"+schemaName, existing.Value)
+		}
+
 		schema := openapi3.NewObjectSchema()
+		if g.strictObjects {
+			schema.AdditionalProperties.Has = ptr(false)
+		}
 
 		// Iterate over the struct fields.
 		for i := 0; i < t.NumField(); i++ {
@@ -265,6 +725,19 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 				continue
 			}
 
+			// Blank fields carry the tags of the struct itself, e.g. _ struct{} `oai:"additionalProperties=false"`.
+			if f.Name == "_" {
+				if v, ok := newTagsResolver(f).pairs[propAdditionalProperties]; ok {
+					schema.AdditionalProperties.Has = ptr(toBool(v))
+				}
+				continue
+			}
+
+			// Skip the unexported fields if requested, the embedded structs may still promote exported fields.
+			if g.excludeUnexported && !f.IsExported() && !f.Anonymous {
+				continue
+			}
+
 			// Handle embedded structs.
 			if f.Anonymous {
 				embedSchema := derefSchema(g.doc, g.generateSchemaRef(parents, f.Type, nameTag))
@@ -276,22 +749,38 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 			}
 
 			// Generate a schema for the field.
-			fieldSchema := g.generateSchemaRef(parents, f.Type, nameTag)
+			fieldSchema := g.generateFieldSchemaRef(parents, f, nameTag)
+			if fieldSchema == nil {
+				continue
+			}
 			// Create a field resolver to handle OpenAPI tags.
 			field := newTagsResolver(f)
 			if fieldSchema.Value != nil {
+				if g.pointersNullable && f.Type.Kind() == reflect.Ptr && fieldSchema.Ref == "" {
+					fieldSchema.Value.Nullable = true
+				}
 				field.injectOAITags(derefSchema(g.doc, fieldSchema))
 			}
+			if g.pointersNullable && f.Type.Kind() == reflect.Ptr && fieldSchema.Ref != "" {
+				fieldSchema = nullableRef(field, fieldSchema)
+			}
 
 			// Add the field to the schema properties.
 			schema.Properties[field.name(nameTag)] = fieldSchema
-			if field.required() {
+			if g.required(field) {
 				schema.Required = append(schema.Required, field.name(nameTag))
 			}
 		}
 
-		// Generate a name for the schema and add it to the OpenAPI components.
-		schemaName := g.generateSchemaName(t, name...)
+		// Add the schema to the OpenAPI components.
+		if other, ok := g.schemaTypes[schemaName]; ok && other != t {
+			panic(fmt.Sprintf("schema name %q collides: it is used by both %s and %s", schemaName, other, t))
+		}
+		if variant, ok := g.schemaVariants[schemaName]; ok {
+			// the variant makes way for the type, e.g. for a UserResponse type generated after the response variant of User
+			g.renameVariant(schemaName, variant)
+		}
+		g.schemaTypes[schemaName] = t
 		g.doc.Components.Schemas[schemaName] = schema.NewRef()
 		return openapi3.NewSchemaRef("#/components/schemas/"+schemaName, schema)
 	}
@@ -299,6 +788,222 @@ func (g *Generator) generateSchemaRef(parents []reflect.Type, t reflect.Type, na
 	panic("unsupported type " + t.String())
 }
 
+// nullableRef wraps a referenced schema in a nullable allOf, since the shared component must not be nullable itself.
+// An explicit `oai:"nullable=false"` tag keeps the reference as is.
+func nullableRef(field *tagsResolver, ref *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if v, ok := field.pairs[propNullable]; ok && !toBool(v) {
+		return ref
+	}
+	schema := openapi3.NewSchema()
+	schema.Nullable = true
+	schema.AllOf = openapi3.SchemaRefs{ref}
+	return schema.NewRef()
+}
+
+// nullableItems marks the items schema of an array of pointers nullable, without altering the shared schemas.
+func nullableItems(items *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if items.Ref != "" {
+		return nullableRef(&tagsResolver{}, items)
+	}
+	value := *items.Value
+	value.Nullable = true
+	return value.NewRef()
+}
+
+// isReadOnly and isWriteOnly report the properties left out of the request and response variants of the schemas.
+func isReadOnly(schema *openapi3.Schema) bool  { return schema.ReadOnly }
+func isWriteOnly(schema *openapi3.Schema) bool { return schema.WriteOnly }
+
+// resolveSchema returns the value of the schema reference, or nil if the referenced component is not registered yet.
+func (g *Generator) resolveSchema(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref.Value != nil || ref.Ref == "" {
+		return ref.Value
+	}
+	if component, ok := g.doc.Components.Schemas[path.Base(ref.Ref)]; ok {
+		return component.Value
+	}
+	return nil
+}
+
+// hasDropped checks if the schema has a property, possibly nested, dropped by drop.
+func (g *Generator) hasDropped(ref *openapi3.SchemaRef, drop func(*openapi3.Schema) bool, visited []*openapi3.Schema) bool {
+	schema := g.resolveSchema(ref)
+	if schema == nil || slices.Contains(visited, schema) {
+		return false
+	}
+	visited = append(visited, schema)
+	for _, property := range schema.Properties {
+		if value := g.resolveSchema(property); value != nil && drop(value) || g.hasDropped(property, drop, visited) {
+			return true
+		}
+	}
+	for _, sub := range subSchemas(schema) {
+		if g.hasDropped(sub, drop, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// subSchemas returns the schemas nested in the schema, other than its properties.
+func subSchemas(schema *openapi3.Schema) openapi3.SchemaRefs {
+	var subs openapi3.SchemaRefs
+	if schema.Items != nil {
+		subs = append(subs, schema.Items)
+	}
+	if schema.AdditionalProperties.Schema != nil {
+		subs = append(subs, schema.AdditionalProperties.Schema)
+	}
+	subs = append(subs, schema.AllOf...)
+	subs = append(subs, schema.OneOf...)
+	return append(subs, schema.AnyOf...)
+}
+
+// readWriteVariant returns the schema without the properties dropped by drop, e.g. the read-only ones in a request.
+// The components which differ are registered under their name suffixed with suffix, e.g. UserRequest,
+// so that the component itself is left unchanged. The schema is returned as is if nothing is dropped.
+func (g *Generator) readWriteVariant(ref *openapi3.SchemaRef, suffix string, drop func(*openapi3.Schema) bool) *openapi3.SchemaRef {
+	if ref == nil || !g.hasDropped(ref, drop, nil) {
+		return ref
+	}
+	if ref.Ref == "" {
+		schema := *ref.Value
+		g.dropProperties(&schema, suffix, drop)
+		return schema.NewRef()
+	}
+
+	base := path.Base(ref.Ref)
+	name := base + suffix
+	if _, ok := g.schemaTypes[name]; ok {
+		// a type is already named as the variant, e.g. UserResponse
+		name = variantName(base, suffix)
+	}
+	if _, ok := g.schemaVariants[name]; ok {
+		return g.variantRef(name)
+	}
+	schema := *g.resolveSchema(ref)
+	// the variant is registered first, so that the recursive references resolve to it
+	g.doc.Components.Schemas[name] = schema.NewRef()
+	g.schemaVariants[name] = &schemaVariant{base: base, suffix: suffix}
+	g.dropProperties(&schema, suffix, drop)
+	return g.variantRef(name)
+}
+
+// schemaVariant is a request or response variant of a component.
+type schemaVariant struct {
+	base   string
+	suffix string
+	// refs are the references to the variant, updated when the variant is renamed.
+	refs []*openapi3.SchemaRef
+}
+
+// variantName returns the name of a variant which cannot collide with the name of a Go type.
+func variantName(base, suffix string) string {
+	return base + "-" + suffix
+}
+
+// variantRef returns a new reference to the variant, tracked so that it follows a rename of the variant.
+func (g *Generator) variantRef(name string) *openapi3.SchemaRef {
+	ref := openapi3.NewSchemaRef("#/components/schemas/"+name, g.doc.Components.Schemas[name].Value)
+	variant := g.schemaVariants[name]
+	variant.refs = append(variant.refs, ref)
+	return ref
+}
+
+// renameVariant renames a variant whose name is taken by a type generated after it, updating its references.
+func (g *Generator) renameVariant(name string, variant *schemaVariant) {
+	renamed := variantName(variant.base, variant.suffix)
+	g.doc.Components.Schemas[renamed] = g.doc.Components.Schemas[name]
+	delete(g.doc.Components.Schemas, name)
+	g.schemaVariants[renamed] = variant
+	delete(g.schemaVariants, name)
+	for _, ref := range variant.refs {
+		ref.Ref = "#/components/schemas/" + renamed
+	}
+}
+
+// dropProperties removes the properties dropped by drop from the schema, replacing the nested schemas by their variant.
+// The schema is altered in place, its properties and nested schemas are not.
+func (g *Generator) dropProperties(schema *openapi3.Schema, suffix string, drop func(*openapi3.Schema) bool) {
+	if schema.Properties != nil {
+		properties := make(openapi3.Schemas, len(schema.Properties))
+		for name, property := range schema.Properties {
+			if value := g.resolveSchema(property); value != nil && drop(value) {
+				continue
+			}
+			properties[name] = g.readWriteVariant(property, suffix, drop)
+		}
+		var required []string
+		for _, name := range schema.Required {
+			if _, ok := properties[name]; ok {
+				required = append(required, name)
+			}
+		}
+		schema.Properties, schema.Required = properties, required
+	}
+	schema.Items = g.readWriteVariant(schema.Items, suffix, drop)
+	schema.AdditionalProperties.Schema = g.readWriteVariant(schema.AdditionalProperties.Schema, suffix, drop)
+	for _, refs := range []*openapi3.SchemaRefs{&schema.AllOf, &schema.OneOf, &schema.AnyOf} {
+		if len(*refs) == 0 {
+			continue
+		}
+		variants := make(openapi3.SchemaRefs, len(*refs))
+		for i, ref := range *refs {
+			variants[i] = g.readWriteVariant(ref, suffix, drop)
+		}
+		*refs = variants
+	}
+}
+
+// generateFieldSchemaRef generates an OpenAPI schema for a struct field.
+// If an error handler is configured, a failure is reported to it and nil is returned,
+// so that the field is skipped instead of panicking.
+func (g *Generator) generateFieldSchemaRef(parents []reflect.Type, f reflect.StructField, nameTag string) (ref *openapi3.SchemaRef) {
+	if g.errorHandler != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				g.errorHandler(fmt.Errorf("field %s: %w", f.Name, toError(r)))
+				ref = nil
+			}
+		}()
+	}
+	t := f.Type
+	// interface fields can document a concrete registered type with `oai:"schemaOf=Name"`
+	if name, ok := newTagsResolver(f).pairs[propSchemaOf]; ok && t.Kind() == reflect.Interface {
+		typ, ok := g.schemaOfTypes[name]
+		if !ok {
+			panic("schemaOf type " + name + " is not registered")
+		}
+		t = typ
+	}
+	return g.generateSchemaRef(parents, t, nameTag)
+}
+
+// RegisterSchemaOf registers a model under the given name,
+// so that interface fields tagged with `oai:"schemaOf=name"` document the model's schema.
+func (g *Generator) RegisterSchemaOf(name string, model any) *Generator {
+	g.schemaOfTypes[name] = reflect.TypeOf(model)
+	return g
+}
+
+// RegisterTypeName names the component schema of the given type, overriding the reflected name and the schema namer.
+// It must be called before the type is first generated.
+func (g *Generator) RegisterTypeName(t reflect.Type, name string) *Generator {
+	g.typeNames[t] = name
+	return g
+}
+
+// componentName returns the component name of a struct schema generated with the given name tag.
+// The schemas named after another tag than json, e.g. the XML ones or the deep object query parameters,
+// are registered apart from the JSON ones under a suffixed name, as their properties are named differently.
+func (g *Generator) componentName(t reflect.Type, nameTag string, name ...string) string {
+	schemaName := g.generateSchemaName(t, name...)
+	if len(name) == 0 && slices.Contains([]string{"xml", QueryTag, HeaderTag, CookieTag, PathTag}, nameTag) {
+		schemaName += "-" + nameTag
+	}
+	return g.componentPrefix + schemaName
+}
+
 // generateSchemaName generates a name for an OpenAPI schema based on the given type.
 // It takes in the type to generate a name for and an optional name to use instead of generating one.
 // It returns a string representing the generated schema name.
@@ -308,6 +1013,16 @@ func (g *Generator) generateSchemaName(t reflect.Type, name ...string) string {
 		return name[0]
 	}
 
+	// Use the name registered for the type.
+	if name, ok := g.typeNames[t]; ok {
+		return name
+	}
+
+	// Use the custom naming strategy if one was configured.
+	if g.schemaNamer != nil {
+		return g.schemaNamer(t)
+	}
+
 	// Generate a name based on the type's package path.
 	if t.PkgPath() != "" {
 		name := t.String()
@@ -318,6 +1033,7 @@ func (g *Generator) generateSchemaName(t reflect.Type, name ...string) string {
 		if name == "" {
 			name = "Object"
 		}
+		name = genericNameReplacer.Replace(regexTypeArg.ReplaceAllStringFunc(name, qualifyTypeArg))
 		return regexSchemaName.ReplaceAllString(name, "")
 	}
 
@@ -338,3 +1054,29 @@ func GenerateSchemaRef(model any, nameTag string, name ...string) *openapi3.Sche
 	// Return the generated schema.
 	return ref
 }
+
+// GenerateSchemaRefE is like GenerateSchemaRef, but returns an error instead of panicking
+// when the model cannot be represented as an OpenAPI schema.
+func GenerateSchemaRefE(model any, nameTag string, name ...string) (*openapi3.SchemaRef, error) {
+	return NewGenerator().GenerateSchemaRefE(model, nameTag, name...)
+}
+
+// GenerateSchemaRefE generates an OpenAPI schema for a given model and registers it in the generator's document.
+// It returns an error instead of panicking when the model cannot be represented as an OpenAPI schema.
+func (g *Generator) GenerateSchemaRefE(model any, nameTag string, name ...string) (ref *openapi3.SchemaRef, err error) {
+	if model == nil {
+		return nil, errors.New("unsupported type nil")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ref, err = nil, toError(r)
+		}
+	}()
+	return g.generateSchemaRef(nil, reflect.TypeOf(model), nameTag, name...), nil
+}
+
+// TypeForSchema returns the Go type a component schema of the document was generated from.
+func (g *Generator) TypeForSchema(name string) (reflect.Type, bool) {
+	t, ok := g.schemaTypes[name]
+	return t, ok
+}
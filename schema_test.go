@@ -1,6 +1,7 @@
 package soda_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"math"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
 	"github.com/neo-f/soda/v3"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -23,6 +25,88 @@ func (c case4) JSONSchema(t *openapi3.T) *openapi3.SchemaRef {
 		NewRef()
 }
 
+type (
+	catEvent struct {
+		Kind  string `json:"kind"`
+		Lives int    `json:"lives"`
+	}
+	dogEvent struct {
+		Kind  string `json:"kind"`
+		Breed string `json:"breed"`
+	}
+	petEvent struct{}
+)
+
+func (petEvent) OneOf(*openapi3.T) []*openapi3.SchemaRef {
+	return []*openapi3.SchemaRef{
+		soda.GenerateSchemaRef(catEvent{}, "json"),
+		soda.GenerateSchemaRef(dogEvent{}, "json"),
+	}
+}
+
+func (petEvent) Discriminator() *openapi3.Discriminator {
+	return soda.NewDiscriminator("kind", map[string]string{"cat": "soda_test.catEvent", "dog": "soda_test.dogEvent"})
+}
+
+// walkCounter counts how many times its schema is generated.
+type walkCounter struct{}
+
+var walkCount int
+
+func (walkCounter) JSONSchema(*openapi3.T) *openapi3.SchemaRef {
+	walkCount++
+	return openapi3.NewStringSchema().NewRef()
+}
+
+type cachedDTO struct {
+	Counter walkCounter `json:"counter"`
+	Next    *cachedDTO  `json:"next"`
+}
+
+type order struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items"`
+}
+
+func (order) Examples() map[string]any {
+	return map[string]any{
+		"empty": order{ID: 1, Items: []string{}},
+		"full":  order{ID: 2, Items: []string{"soda"}},
+	}
+}
+
+type tree[T any] struct {
+	Value    T         `json:"value"`
+	Children []tree[T] `json:"children"`
+}
+
+type (
+	ping[T any] struct {
+		Value T        `json:"value"`
+		Pong  *pong[T] `json:"pong"`
+	}
+	pong[T any] struct {
+		Ping *ping[T] `json:"ping"`
+	}
+)
+
+func BenchmarkGenerateSchemaRef(b *testing.B) {
+	type address struct {
+		Street string `json:"street"`
+		City   string `json:"city"`
+	}
+	type user struct {
+		Name      string    `json:"name"`
+		Addresses []address `json:"addresses"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	g := soda.NewGenerator()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.GenerateResponse(200, user{}, "application/json", "")
+	}
+}
+
 func TestGenerator(t *testing.T) {
 	Convey("Given a soda generator", t, func() {
 		g := soda.NewGenerator()
@@ -82,6 +166,36 @@ func TestGenerator(t *testing.T) {
 				So(schema, ShouldResemble, openapi3.NewStringSchema().WithFormat("date-time").NewRef())
 			})
 
+			Convey("It should return the correct schema for time.Duration", func() {
+				type TestCase struct {
+					Timeout time.Duration `json:"timeout"`
+				}
+				schema := soda.GenerateSchemaRef(TestCase{}, "json")
+				So(schema.Value.Properties["timeout"].Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("duration"))
+
+				schema, _ = soda.NewGenerator(soda.WithDurationAsInteger()).GenerateSchemaRefE(TestCase{}, "json")
+				expected := openapi3.NewInt64Schema()
+				expected.Description = "duration in nanoseconds"
+				So(schema.Value.Properties["timeout"].Value, ShouldResemble, expected)
+			})
+
+			Convey("It should return nullable scalars for the database/sql nullable types", func() {
+				type TestCase struct {
+					String sql.NullString  `json:"string"`
+					Int    sql.NullInt64   `json:"int"`
+					Float  sql.NullFloat64 `json:"float"`
+					Bool   sql.NullBool    `json:"bool"`
+					Time   sql.NullTime    `json:"time"`
+				}
+				schema := soda.GenerateSchemaRef(TestCase{}, "json")
+				properties := schema.Value.Properties
+				So(properties["string"].Value, ShouldResemble, openapi3.NewStringSchema().WithNullable())
+				So(properties["int"].Value, ShouldResemble, openapi3.NewInt64Schema().WithMin(math.MinInt64).WithMax(math.MaxInt64).WithNullable())
+				So(properties["float"].Value, ShouldResemble, openapi3.NewFloat64Schema().WithNullable())
+				So(properties["bool"].Value, ShouldResemble, openapi3.NewBoolSchema().WithNullable())
+				So(properties["time"].Value, ShouldResemble, openapi3.NewDateTimeSchema().WithNullable())
+			})
+
 			Convey("It should return the correct schema for net.IP", func() {
 				schema := soda.GenerateSchemaRef(net.IP{}, "")
 				So(schema, ShouldResemble, openapi3.NewStringSchema().WithFormat("ipv4").NewRef())
@@ -97,6 +211,16 @@ func TestGenerator(t *testing.T) {
 				So(schema, ShouldResemble, openapi3.NewBytesSchema().NewRef())
 			})
 
+			Convey("It should return the correct format for []byte fields", func() {
+				type TestCase struct {
+					A []byte `json:"a"`
+					B []byte `json:"b" oai:"format=binary"`
+				}
+				schema := soda.GenerateSchemaRef(TestCase{}, "json")
+				So(schema.Value.Properties["a"].Value, ShouldResemble, openapi3.NewBytesSchema())
+				So(schema.Value.Properties["b"].Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("binary"))
+			})
+
 			Convey("It should return the correct schema for array", func() {
 				schema := soda.GenerateSchemaRef([2]int{}, "")
 				expected := openapi3.NewArraySchema().
@@ -258,6 +382,75 @@ func TestGenerator(t *testing.T) {
 				So(schema.Value, ShouldEqual, expected)
 			})
 
+			Convey("It should return the registered schema for interface fields with schemaOf", func() {
+				type Pet struct {
+					Name string `json:"name"`
+				}
+				type TestCase struct {
+					A any `json:"a" oai:"schemaOf=Pet"`
+					B any `json:"b"`
+				}
+				g := soda.NewGenerator().RegisterSchemaOf("Pet", Pet{})
+				schema, err := g.GenerateSchemaRefE(TestCase{}, "json")
+				So(err, ShouldBeNil)
+				So(schema.Value.Properties["a"].Ref, ShouldEqual, "#/components/schemas/soda_test.Pet")
+				So(schema.Value.Properties["b"].Value, ShouldResemble, openapi3.NewSchema())
+
+				_, err = soda.NewGenerator().GenerateSchemaRefE(TestCase{}, "json")
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("It should look up the type of a generated schema by its name", func() {
+				type Pet struct {
+					Name string `json:"name"`
+				}
+				g := soda.NewGenerator()
+				_, err := g.GenerateSchemaRefE(Pet{}, "json")
+				So(err, ShouldBeNil)
+				t, ok := g.TypeForSchema("soda_test.Pet")
+				So(ok, ShouldBeTrue)
+				So(t, ShouldEqual, reflect.TypeOf(Pet{}))
+
+				_, ok = g.TypeForSchema("soda_test.Unknown")
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("It should reference the recursive generic types by their instantiation", func() {
+				// the type arguments are named by their package name, as the generic type itself
+				schema, err := soda.NewGenerator().GenerateSchemaRefE(tree[tree[int]]{}, "json")
+				So(err, ShouldBeNil)
+				So(schema.Ref, ShouldEqual, "#/components/schemas/soda_test.tree-soda_test.tree-int")
+				So(schema.Value.Properties["children"].Value.Items.Ref, ShouldEqual, "#/components/schemas/soda_test.tree-soda_test.tree-int")
+				value := schema.Value.Properties["value"]
+				So(value.Ref, ShouldEqual, "#/components/schemas/soda_test.tree-int")
+				So(value.Value.Properties["children"].Value.Items.Ref, ShouldEqual, "#/components/schemas/soda_test.tree-int")
+
+				// the major version suffix of the module is not part of the package name
+				schema, err = soda.NewGenerator().GenerateSchemaRefE(tree[fiber.Map]{}, "json")
+				So(err, ShouldBeNil)
+				So(schema.Ref, ShouldEqual, "#/components/schemas/soda_test.tree-fiber.Map")
+
+				g := soda.NewGenerator()
+				schema, err = g.GenerateSchemaRefE(ping[string]{}, "json")
+				So(err, ShouldBeNil)
+				So(schema.Ref, ShouldEqual, "#/components/schemas/soda_test.ping-string")
+				pongRef := schema.Value.Properties["pong"]
+				So(pongRef.Ref, ShouldEqual, "#/components/schemas/soda_test.pong-string")
+				So(pongRef.Value.Properties["ping"].Ref, ShouldEqual, "#/components/schemas/soda_test.ping-string")
+				t, _ := g.TypeForSchema("soda_test.pong-string")
+				So(t, ShouldEqual, reflect.TypeOf(pong[string]{}))
+			})
+
+			Convey("It should fail on types nested too deeply", func() {
+				t := reflect.TypeOf(0)
+				for i := 0; i < 100; i++ {
+					t = reflect.SliceOf(t)
+				}
+				_, err := soda.NewGenerator().GenerateSchemaRefE(reflect.New(t).Elem().Interface(), "json")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "nested deeper than 64 levels")
+			})
+
 			Convey("It should panic for unsupported types", func() {
 				So(func() { soda.GenerateSchemaRef(nil, "") }, ShouldPanic)
 				So(func() { soda.GenerateSchemaRef(make(chan int), "") }, ShouldPanic)
@@ -265,6 +458,471 @@ func TestGenerator(t *testing.T) {
 		})
 	})
 
+	Convey("Given the error returning generation path", t, func() {
+		Convey("It should return an error for unsupported types", func() {
+			_, err := soda.GenerateSchemaRefE(make(chan int), "")
+			So(err, ShouldNotBeNil)
+			_, err = soda.GenerateSchemaRefE(nil, "")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("It should return the schema for supported types", func() {
+			schema, err := soda.GenerateSchemaRefE("", "")
+			So(err, ShouldBeNil)
+			So(schema, ShouldResemble, openapi3.NewStringSchema().NewRef())
+		})
+
+		Convey("It should skip the bad field with an error handler", func() {
+			type testCase struct {
+				A string   `json:"a"`
+				B chan int `json:"b"`
+			}
+			var errs []error
+			g := soda.NewGenerator(soda.WithErrorHandler(func(err error) {
+				errs = append(errs, err)
+			}))
+			schema, err := g.GenerateSchemaRefE(testCase{}, "json")
+			So(err, ShouldBeNil)
+			So(errs, ShouldHaveLength, 1)
+			So(schema.Value.Properties, ShouldContainKey, "a")
+			So(schema.Value.Properties, ShouldNotContainKey, "b")
+		})
+	})
+
+	Convey("Given a struct used by several operations", t, func() {
+		walkCount = 0
+		g := soda.NewGenerator()
+		first := g.GenerateResponse(200, cachedDTO{}, "application/json", "")
+		second := g.GenerateResponse(201, cachedDTO{}, "application/json", "")
+
+		Convey("It should not walk the struct again", func() {
+			So(walkCount, ShouldEqual, 1)
+		})
+
+		Convey("It should reference the same component", func() {
+			firstRef := first.Content.Get("application/json").Schema
+			secondRef := second.Content.Get("application/json").Schema
+			So(secondRef.Ref, ShouldEqual, firstRef.Ref)
+			So(secondRef.Value, ShouldEqual, firstRef.Value)
+			So(secondRef.Value.Properties["next"].Ref, ShouldEqual, "#/components/schemas/soda_test.cachedDTO")
+		})
+	})
+
+	Convey("Given a type implementing OneOf", t, func() {
+		engine := soda.New()
+		engine.Get("/events", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, petEvent{}).OK()
+		schema := engine.OpenAPI().Paths.Find("/events").Get.Responses.Status(200).Value.Content.Get("application/json").Schema
+
+		Convey("It should produce a oneOf of the sub-schemas", func() {
+			So(schema.Ref, ShouldBeEmpty)
+			So(schema.Value.OneOf, ShouldHaveLength, 2)
+			So(schema.Value.OneOf[0].Ref, ShouldEqual, "#/components/schemas/soda_test.catEvent")
+			So(schema.Value.OneOf[1].Ref, ShouldEqual, "#/components/schemas/soda_test.dogEvent")
+		})
+
+		Convey("It should register the sub-schemas in components", func() {
+			schemas := engine.OpenAPI().Components.Schemas
+			So(schemas, ShouldContainKey, "soda_test.catEvent")
+			So(schemas, ShouldContainKey, "soda_test.dogEvent")
+			So(schemas["soda_test.dogEvent"].Value.Properties, ShouldContainKey, "breed")
+		})
+
+		Convey("It should set the discriminator", func() {
+			So(schema.Value.Discriminator, ShouldResemble, &openapi3.Discriminator{
+				PropertyName: "kind",
+				Mapping: map[string]string{
+					"cat": "#/components/schemas/soda_test.catEvent",
+					"dog": "#/components/schemas/soda_test.dogEvent",
+				},
+			})
+		})
+	})
+
+	Convey("Given a type with a registered example", t, func() {
+		type product struct {
+			Name  string  `json:"name"`
+			Price float64 `json:"price"`
+		}
+		type sku string
+		example := product{Name: "soda", Price: 1.5}
+		soda.RegisterExample(reflect.TypeOf(product{}), example)
+		soda.RegisterExample(reflect.TypeOf(sku("")), sku("SKU-1"))
+
+		Convey("It should attach the example to the schema", func() {
+			schema := soda.GenerateSchemaRef(&product{}, "json")
+			So(schema.Value.Example, ShouldResemble, example)
+			So(soda.GenerateSchemaRef(sku(""), "json").Value.Example, ShouldEqual, sku("SKU-1"))
+		})
+
+		Convey("It should serialize the example with the schema", func() {
+			data, err := soda.GenerateSchemaRef(product{}, "json").Value.MarshalJSON()
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, `"example":{"name":"soda","price":1.5}`)
+		})
+	})
+
+	Convey("Given a generator with auto request examples", t, func() {
+		type address struct {
+			City string `json:"city" oai:"example=Paris"`
+			Zip  string `json:"zip"`
+		}
+		type body struct {
+			Name    string   `json:"name"    oai:"example=soda"`
+			Page    int      `json:"page"    oai:"default=1"`
+			Size    int      `json:"size"    oai:"default=10;example=20"`
+			Tags    []string `json:"tags"`
+			Address address  `json:"address"`
+		}
+		g := soda.NewGenerator(soda.WithAutoRequestExample())
+
+		Convey("It should compose the example from the field examples and defaults", func() {
+			requestBody := g.GenerateRequestBody("create", "json", reflect.TypeOf(body{}))
+			So(requestBody.Content.Get("application/json").Example, ShouldResemble, map[string]any{
+				"name":    "soda",
+				"page":    1,
+				"size":    20,
+				"address": map[string]any{"city": "Paris"},
+			})
+		})
+
+		Convey("It should not set an example without the option", func() {
+			requestBody := soda.NewGenerator().GenerateRequestBody("create", "json", reflect.TypeOf(body{}))
+			So(requestBody.Content.Get("application/json").Example, ShouldBeNil)
+		})
+	})
+
+	Convey("Given a model providing named examples", t, func() {
+		engine := soda.New(soda.WithAutoRequestExample(), soda.WithInfo("orders", "1.0.0"))
+		engine.Post("/orders", func(c *fiber.Ctx) error { return nil }).
+			SetInput(&struct {
+				Body order `body:"json"`
+			}{}).
+			AddJSONResponse(201, order{}).
+			OK()
+		operation := engine.OpenAPI().Paths.Find("/orders").Post
+
+		Convey("It should document them on the JSON media type of the request body", func() {
+			mediaType := operation.RequestBody.Value.Content.Get("application/json")
+			So(mediaType.Example, ShouldBeNil)
+			So(mediaType.Examples, ShouldHaveLength, 2)
+			So(mediaType.Examples["empty"].Value.Value, ShouldResemble, map[string]any{"id": 1.0, "items": []any{}})
+			So(mediaType.Examples["full"].Value.Value, ShouldResemble, map[string]any{"id": 2.0, "items": []any{"soda"}})
+		})
+
+		Convey("It should document them on the JSON media type of the response", func() {
+			mediaType := operation.Responses.Status(201).Value.Content.Get("application/json")
+			So(mediaType.Examples, ShouldHaveLength, 2)
+			So(mediaType.Examples["full"].Value.Value, ShouldResemble, map[string]any{"id": 2.0, "items": []any{"soda"}})
+		})
+
+		Convey("It should keep the spec valid", func() {
+			So(engine.Validate(), ShouldBeNil)
+		})
+	})
+
+	Convey("Given a generator with pointers nullable", t, func() {
+		g := soda.NewGenerator(soda.WithPointersNullable())
+
+		Convey("It should mark the pointer fields nullable", func() {
+			type Child struct {
+				A string `json:"a"`
+			}
+			type TestCase struct {
+				Int      *int      `json:"int"`
+				Strings  *[]string `json:"strings"`
+				Child    *Child    `json:"child"`
+				Plain    int       `json:"plain"`
+				Explicit *int      `json:"explicit" oai:"nullable=false"`
+			}
+			schema, err := g.GenerateSchemaRefE(TestCase{}, "json")
+			So(err, ShouldBeNil)
+			properties := schema.Value.Properties
+			So(properties["int"].Value, ShouldResemble, openapi3.NewIntegerSchema().WithNullable())
+			So(properties["strings"].Value, ShouldResemble, openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()).WithNullable())
+			So(properties["plain"].Value.Nullable, ShouldBeFalse)
+			So(properties["explicit"].Value.Nullable, ShouldBeFalse)
+
+			So(properties["child"].Value.Nullable, ShouldBeTrue)
+			So(properties["child"].Value.AllOf, ShouldHaveLength, 1)
+			So(properties["child"].Value.AllOf[0].Ref, ShouldEqual, "#/components/schemas/soda_test.Child")
+			So(properties["child"].Value.AllOf[0].Value.Nullable, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a generator with compact arrays", t, func() {
+		type Item struct {
+			A string `json:"a"`
+		}
+		type TestCase struct {
+			Strings []*string `json:"strings"`
+			Items   []*Item   `json:"items"`
+			Plain   []string  `json:"plain"`
+		}
+
+		Convey("It should preserve the nullability of the pointer items", func() {
+			schema, err := soda.NewGenerator(soda.WithCompactArrays()).GenerateSchemaRefE(TestCase{}, "json")
+			So(err, ShouldBeNil)
+			properties := schema.Value.Properties
+			So(properties["strings"].Value.Items.Value, ShouldResemble, openapi3.NewStringSchema().WithNullable())
+			So(properties["plain"].Value.Items.Value.Nullable, ShouldBeFalse)
+
+			items := properties["items"].Value.Items.Value
+			So(items.Nullable, ShouldBeTrue)
+			So(items.AllOf, ShouldHaveLength, 1)
+			So(items.AllOf[0].Ref, ShouldEqual, "#/components/schemas/soda_test.Item")
+			So(items.AllOf[0].Value.Nullable, ShouldBeFalse)
+		})
+
+		Convey("It should collapse the pointer items without the option", func() {
+			schema, err := soda.NewGenerator().GenerateSchemaRefE(TestCase{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.Properties["strings"].Value.Items.Value, ShouldResemble, openapi3.NewStringSchema())
+			So(schema.Value.Properties["items"].Value.Items.Ref, ShouldEqual, "#/components/schemas/soda_test.Item")
+		})
+	})
+
+	Convey("Given structs closed to additional properties", t, func() {
+		type Strict struct {
+			_ struct{} `oai:"additionalProperties=false"`
+			A string   `json:"a"`
+		}
+		type Open struct {
+			_ struct{}       `oai:"additionalProperties=true"`
+			A string         `json:"a"`
+			M map[string]int `json:"m"`
+		}
+
+		Convey("It should close the struct tagged on a blank field", func() {
+			schema, err := soda.NewGenerator().GenerateSchemaRefE(Strict{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.AdditionalProperties.Has, ShouldResemble, openapi3.BoolPtr(false))
+			So(schema.Value.Properties, ShouldHaveLength, 1)
+			data, _ := schema.Value.MarshalJSON()
+			So(string(data), ShouldContainSubstring, `"additionalProperties":false`)
+		})
+
+		Convey("It should close all the structs with the strict objects option", func() {
+			type Parent struct {
+				Child Strict         `json:"child"`
+				M     map[string]int `json:"m"`
+			}
+			g := soda.NewGenerator(soda.WithStrictObjects())
+			schema, err := g.GenerateSchemaRefE(Parent{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.AdditionalProperties.Has, ShouldResemble, openapi3.BoolPtr(false))
+			So(schema.Value.Properties["child"].Value.AdditionalProperties.Has, ShouldResemble, openapi3.BoolPtr(false))
+			So(schema.Value.Properties["m"].Value.AdditionalProperties.Has, ShouldBeNil)
+			So(schema.Value.Properties["m"].Value.AdditionalProperties.Schema, ShouldNotBeNil)
+
+			open, err := g.GenerateSchemaRefE(Open{}, "json")
+			So(err, ShouldBeNil)
+			So(open.Value.AdditionalProperties.Has, ShouldResemble, openapi3.BoolPtr(true))
+		})
+	})
+
+	Convey("Given structs with unexported fields", t, func() {
+		type base struct {
+			ID int `json:"id"`
+		}
+		type Account struct {
+			base
+			Name   string `json:"name"`
+			secret string
+		}
+
+		Convey("It should keep them by default", func() {
+			schema, err := soda.NewGenerator().GenerateSchemaRefE(Account{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.Properties, ShouldContainKey, "secret")
+		})
+
+		Convey("It should skip them with the exclude unexported fields option", func() {
+			engine := soda.New(soda.WithExcludeUnexportedFields())
+			engine.Get("/account", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, Account{}).OK()
+			schema := engine.OpenAPI().Components.Schemas["soda_test.Account"].Value
+			So(schema.Properties, ShouldNotContainKey, "secret")
+			So(schema.Properties, ShouldContainKey, "name")
+			So(schema.Properties, ShouldContainKey, "id")
+		})
+
+		Convey("It should still honor the struct-level tags of the blank fields", func() {
+			type Settings struct {
+				_      struct{} `oai:"additionalProperties=true"`
+				Theme  string   `json:"theme"`
+				secret string
+			}
+			engine := soda.New(soda.WithStrictObjects(), soda.WithExcludeUnexportedFields())
+			engine.Get("/settings", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, Settings{}).OK()
+			schema := engine.OpenAPI().Components.Schemas["soda_test.Settings"].Value
+			So(schema.AdditionalProperties.Has, ShouldNotBeNil)
+			So(*schema.AdditionalProperties.Has, ShouldBeTrue)
+			So(schema.Properties, ShouldNotContainKey, "secret")
+		})
+
+		Convey("It should never generate them as parameters", func() {
+			type input struct {
+				Page   int `query:"page"`
+				cursor int `query:"cursor"`
+			}
+			parameters := soda.NewGenerator().GenerateParameters(reflect.TypeOf(input{}))
+			So(parameters, ShouldHaveLength, 1)
+			So(parameters[0].Value.Name, ShouldEqual, "page")
+		})
+	})
+
+	Convey("Given a type registered under another name", t, func() {
+		type userDTO struct {
+			Name string `json:"name"`
+		}
+		type Team struct {
+			Owner   userDTO   `json:"owner"`
+			Members []userDTO `json:"members"`
+		}
+
+		Convey("It should use the alias in the components and the refs", func() {
+			g := soda.NewGenerator().RegisterTypeName(reflect.TypeOf(userDTO{}), "User")
+			schema, err := g.GenerateSchemaRefE(Team{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.Properties["owner"].Ref, ShouldEqual, "#/components/schemas/User")
+			So(schema.Value.Properties["members"].Value.Items.Ref, ShouldEqual, "#/components/schemas/User")
+			t, ok := g.TypeForSchema("User")
+			So(ok, ShouldBeTrue)
+			So(t, ShouldEqual, reflect.TypeOf(userDTO{}))
+		})
+
+		Convey("It should be registered through the engine", func() {
+			engine := soda.New().RegisterTypeName(reflect.TypeOf(userDTO{}), "User")
+			engine.Get("/users", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, []userDTO{}).OK()
+			So(engine.OpenAPI().Components.Schemas, ShouldContainKey, "User")
+			So(engine.OpenAPI().Components.Schemas, ShouldNotContainKey, "soda_test.userDTO")
+		})
+	})
+
+	Convey("Given a struct with read-only and write-only fields used both ways", t, func() {
+		type Profile struct {
+			Bio       string `json:"bio"`
+			Token     string `json:"token" oai:"writeOnly"`
+			CreatedAt string `json:"createdAt" oai:"readOnly"`
+		}
+		type User struct {
+			ID       int     `json:"id" oai:"readOnly"`
+			Name     string  `json:"name"`
+			Password string  `json:"password" oai:"writeOnly"`
+			Profile  Profile `json:"profile"`
+		}
+		type input struct {
+			Body User `body:"json"`
+		}
+		engine := soda.New(soda.WithInfo("Users", "1.0.0"))
+		engine.Post("/users", func(c *fiber.Ctx) error { return nil }).
+			SetInput(&input{}).
+			AddJSONResponse(201, User{}).
+			OK()
+		operation := engine.OpenAPI().Paths.Find("/users").Post
+		schemas := engine.OpenAPI().Components.Schemas
+
+		Convey("It should leave the read-only fields out of the request", func() {
+			body := operation.RequestBody.Value.Content["application/json"].Schema.Value
+			So(body.Properties, ShouldNotContainKey, "id")
+			So(body.Properties, ShouldContainKey, "password")
+			So(body.Required, ShouldNotContain, "id")
+			So(body.Properties["profile"].Ref, ShouldEqual, "#/components/schemas/soda_test.ProfileRequest")
+			So(schemas["soda_test.ProfileRequest"].Value.Properties, ShouldNotContainKey, "createdAt")
+			So(schemas["soda_test.ProfileRequest"].Value.Properties, ShouldContainKey, "token")
+		})
+
+		Convey("It should reference a response variant without the write-only fields", func() {
+			response := operation.Responses.Status(201).Value.Content["application/json"].Schema
+			So(response.Ref, ShouldEqual, "#/components/schemas/soda_test.UserResponse")
+			user := schemas["soda_test.UserResponse"].Value
+			So(user.Properties, ShouldNotContainKey, "password")
+			So(user.Properties, ShouldContainKey, "id")
+			So(user.Required, ShouldNotContain, "password")
+			So(user.Properties["profile"].Ref, ShouldEqual, "#/components/schemas/soda_test.ProfileResponse")
+			So(schemas["soda_test.ProfileResponse"].Value.Properties, ShouldNotContainKey, "token")
+		})
+
+		Convey("It should keep the shared component unchanged", func() {
+			So(schemas["soda_test.User"].Value.Properties, ShouldHaveLength, 4)
+			So(schemas["soda_test.Profile"].Value.Properties, ShouldHaveLength, 3)
+			So(engine.Validate(), ShouldBeNil)
+		})
+
+		Convey("It should rename the variant when a type generated later is named as it", func() {
+			type UserResponse struct {
+				Other string `json:"other"`
+			}
+			engine.Get("/other", nil).AddJSONResponse(200, UserResponse{}).OK()
+			So(schemas["soda_test.UserResponse"].Value.Properties, ShouldContainKey, "other")
+			So(schemas["soda_test.User-Response"].Value.Properties, ShouldNotContainKey, "password")
+			response := operation.Responses.Status(201).Value.Content["application/json"].Schema
+			So(response.Ref, ShouldEqual, "#/components/schemas/soda_test.User-Response")
+			So(engine.Validate(), ShouldBeNil)
+		})
+	})
+
+	Convey("Given a type named as the response variant of another type", t, func() {
+		type User struct {
+			Name     string `json:"name"`
+			Password string `json:"password" oai:"writeOnly"`
+		}
+		type UserResponse struct {
+			Users []User `json:"users"`
+		}
+		engine := soda.New(soda.WithInfo("Users", "1.0.0"))
+		engine.Get("/users", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, UserResponse{}).OK()
+		engine.Get("/me", func(c *fiber.Ctx) error { return nil }).AddJSONResponse(200, User{}).OK()
+		schemas := engine.OpenAPI().Components.Schemas
+
+		Convey("It should name the variant so that it does not collide with the type", func() {
+			response := engine.OpenAPI().Paths.Find("/me").Get.Responses.Status(200).Value.Content["application/json"].Schema
+			So(response.Ref, ShouldEqual, "#/components/schemas/soda_test.User-Response")
+			So(schemas["soda_test.User-Response"].Value.Properties, ShouldNotContainKey, "password")
+			So(schemas["soda_test.UserResponse"].Value.Properties, ShouldContainKey, "users")
+			So(engine.Validate(), ShouldBeNil)
+		})
+	})
+
+	Convey("Given a custom schema namer", t, func() {
+		namer := soda.WithSchemaNamer(func(t reflect.Type) string {
+			return "Custom" + t.Name()
+		})
+
+		Convey("It should rename the components and the refs", func() {
+			type Node struct {
+				Children []Node `json:"children"`
+			}
+			type Tree struct {
+				Root Node `json:"root"`
+			}
+			g := soda.NewGenerator(namer)
+			schema, err := g.GenerateSchemaRefE(Tree{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Ref, ShouldEqual, "#/components/schemas/CustomTree")
+			root := schema.Value.Properties["root"]
+			So(root.Ref, ShouldEqual, "#/components/schemas/CustomNode")
+			So(root.Value.Properties["children"].Value.Items.Ref, ShouldEqual, "#/components/schemas/CustomNode")
+		})
+
+		Convey("It should panic on colliding names", func() {
+			g := soda.NewGenerator(namer)
+			type A struct {
+				X string
+			}
+			_, err := g.GenerateSchemaRefE(A{}, "")
+			So(err, ShouldBeNil)
+
+			func() {
+				type A struct {
+					Y int
+				}
+				_, err = g.GenerateSchemaRefE(A{}, "")
+			}()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "CustomA")
+		})
+	})
+
 	Convey("Given parameters generation", t, func() {
 		g := soda.NewGenerator()
 
@@ -435,6 +1093,26 @@ func TestGenerator(t *testing.T) {
 			)
 		})
 
+		Convey("Deprecated fields should be optional with WithDeprecatedOptional", func() {
+			type test struct {
+				A string `json:"a"`
+				B string `json:"b" oai:"deprecated"`
+			}
+
+			resp := g.GenerateResponse(200, test{}, "application/json", "")
+			So(resp.Content.Get("application/json").Schema.Value.Required, ShouldResemble, []string{"a", "b"})
+
+			g := soda.NewGenerator(soda.WithDeprecatedOptional())
+			resp = g.GenerateResponse(200, test{}, "application/json", "")
+			So(resp.Content.Get("application/json").Schema.Value.Required, ShouldResemble, []string{"a"})
+		})
+
+		Convey("Providing a wildcard media-type should generate a binary response", func() {
+			resp := g.GenerateResponse(200, []byte{}, "*/*", "")
+			So(resp.Content, ShouldHaveLength, 1)
+			So(resp.Content.Get("*/*").Schema.Value, ShouldResemble, openapi3.NewStringSchema().WithFormat("binary"))
+		})
+
 		Convey("Providing an unsupported media-type should panic", func() {
 			type test struct {
 				A string `json:"a"`
@@ -12,6 +12,16 @@ func NewJWTSecurityScheme(description ...string) *openapi3.SecurityScheme {
 	return sec
 }
 
+func NewBearerSecurityScheme(description ...string) *openapi3.SecurityScheme {
+	sec := openapi3.NewSecurityScheme().
+		WithType("http").
+		WithScheme("bearer")
+	if len(description) != 0 {
+		sec = sec.WithDescription(description[0])
+	}
+	return sec
+}
+
 func NewAPIKeySecurityScheme(in string, name string, description ...string) *openapi3.SecurityScheme {
 	sec := openapi3.NewSecurityScheme().
 		WithType("apiKey").
@@ -22,3 +32,38 @@ func NewAPIKeySecurityScheme(in string, name string, description ...string) *ope
 	}
 	return sec
 }
+
+// NewOAuth2SecurityScheme creates an oauth2 security scheme with the given flows.
+func NewOAuth2SecurityScheme(flows *openapi3.OAuthFlows, description ...string) *openapi3.SecurityScheme {
+	sec := openapi3.NewSecurityScheme().WithType("oauth2")
+	sec.Flows = flows
+	if len(description) != 0 {
+		sec = sec.WithDescription(description[0])
+	}
+	return sec
+}
+
+// NewOAuth2AuthorizationCodeFlows creates the flows of an oauth2 security scheme using the authorization code flow.
+// The scopes map the scope names to their description.
+func NewOAuth2AuthorizationCodeFlows(authorizationURL, tokenURL string, scopes map[string]string) *openapi3.OAuthFlows {
+	if scopes == nil {
+		scopes = map[string]string{}
+	}
+	return &openapi3.OAuthFlows{
+		AuthorizationCode: &openapi3.OAuthFlow{
+			AuthorizationURL: authorizationURL,
+			TokenURL:         tokenURL,
+			Scopes:           scopes,
+		},
+	}
+}
+
+// NewOpenIDConnectSecurityScheme creates an openIdConnect security scheme discovered from the given URL.
+func NewOpenIDConnectSecurityScheme(url string, description ...string) *openapi3.SecurityScheme {
+	sec := openapi3.NewSecurityScheme().WithType("openIdConnect")
+	sec.OpenIdConnectUrl = url
+	if len(description) != 0 {
+		sec = sec.WithDescription(description[0])
+	}
+	return sec
+}
@@ -1,7 +1,9 @@
 package soda
 
 import (
+	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -25,7 +27,7 @@ func newTagsResolver(f reflect.StructField) *tagsResolver {
 		// Create a map for the tag pairs
 		resolver.pairs = make(map[string]string)
 		// Split the tags and store them in the map
-		for _, tag := range strings.Split(oaiTags, SeparatorProp) {
+		for _, tag := range splitEscaped(oaiTags, SeparatorProp) {
 			tag = strings.TrimSpace(tag)
 			k, v, _ := strings.Cut(tag, "=")
 			resolver.pairs[strings.TrimSpace(k)] = strings.TrimSpace(v)
@@ -49,27 +51,66 @@ func (f tagsResolver) injectOAITags(schema *openapi3.Schema) {
 		f.injectOAIArray(schema)
 	case schema.Type.Is(typeBoolean):
 		f.injectOAIBoolean(schema)
+	case schema.Type.Is(typeObject):
+		f.injectOAIObject(schema)
 	}
 }
 
 // required checks if the field is required.
+// An explicit `oai:"required"` tag wins, then the pointers, the slices or maps allowed to be empty
+// and the fields omitted from the JSON when empty are optional, the other fields are required.
+// Being nullable does not make a field optional, as null is a value the clients must still send.
 func (f tagsResolver) required() bool {
-	// By default, a field is required if it is not a pointer
-	required := f.f.Type.Kind() != reflect.Ptr
-	// Check the 'required' tag
-	if v, ok := f.pairs[propRequired]; ok {
-		required = toBool(v)
+	if required, ok := f.explicitRequired(); ok {
+		return required
 	}
-	return required
+	return f.f.Type.Kind() != reflect.Ptr && !f.allowEmpty() && !f.omitEmpty()
+}
+
+// explicitRequired returns the value of the `oai:"required"` tag, and whether the field is tagged.
+func (f tagsResolver) explicitRequired() (bool, bool) {
+	v, ok := f.pairs[propRequired]
+	return ok && toBool(v), ok
+}
+
+// allowEmpty checks if the field is a slice or map allowed to be empty.
+func (f tagsResolver) allowEmpty() bool {
+	if kind := f.f.Type.Kind(); kind != reflect.Slice && kind != reflect.Map {
+		return false
+	}
+	v, ok := f.pairs[propAllowEmpty]
+	return ok && toBool(v)
+}
+
+// omitEmpty checks if the field is tagged with the json omitempty option.
+func (f tagsResolver) omitEmpty() bool {
+	_, options, _ := strings.Cut(f.f.Tag.Get("json"), ",")
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecated checks if the field is marked as deprecated.
+func (f tagsResolver) deprecated() bool {
+	v, ok := f.pairs[propDeprecated]
+	return ok && toBool(v)
+}
+
+// from returns the input source of the field, e.g. bearer.
+func (f tagsResolver) from() string {
+	return f.pairs[propFrom]
 }
 
 // name returns the name of the field.
 // If the field is tagged with the specified tag, then that tag is used instead.
-// If the tag contains a comma, then only the first part of the tag is used.
+// If the tag contains a comma, then only the first part of the tag is used, unless it is empty.
 func (f tagsResolver) name(tag ...string) string {
 	if len(tag) > 0 {
-		if name := f.f.Tag.Get(tag[0]); name != "" {
-			return strings.Split(name, ",")[0]
+		if name, _, _ := strings.Cut(f.f.Tag.Get(tag[0]), ","); name != "" {
+			return name
 		}
 	}
 	return f.f.Name
@@ -79,6 +120,14 @@ func (f tagsResolver) name(tag ...string) string {
 func (f *tagsResolver) injectOAIGeneric(schema *openapi3.Schema) {
 	// Iterate over the tag pairs and inject them into the schema
 	for tag, val := range f.pairs {
+		// vendor extensions are injected as is, e.g. `oai:"x-go-type=decimal.Decimal"`
+		if strings.HasPrefix(tag, extPrefix) {
+			if schema.Extensions == nil {
+				schema.Extensions = make(map[string]any)
+			}
+			schema.Extensions[tag] = toExtensionValue(val)
+			continue
+		}
 		switch tag {
 		case propTitle:
 			schema.Title = val
@@ -86,12 +135,31 @@ func (f *tagsResolver) injectOAIGeneric(schema *openapi3.Schema) {
 			schema.Description = val
 		case propDeprecated:
 			schema.Deprecated = toBool(val)
+		case propDeprecatedReason:
+			if schema.Extensions == nil {
+				schema.Extensions = make(map[string]any)
+			}
+			schema.Extensions[extDeprecatedReason] = val
 		case propWriteOnly:
 			schema.WriteOnly = toBool(val)
 		case propReadOnly:
 			schema.ReadOnly = toBool(val)
 		case propNullable:
 			schema.Nullable = toBool(val)
+		case propDefault, propExample:
+			// objects and untyped values accept JSON values, e.g. `oai:"example={\"a\":1}"`
+			if schema.Type != nil && !schema.Type.Is(typeObject) {
+				continue
+			}
+			value, ok := toJSONComposite(val)
+			if !ok {
+				continue
+			}
+			if tag == propDefault {
+				schema.Default = value
+			} else {
+				schema.Example = value
+			}
 		}
 	}
 }
@@ -110,6 +178,10 @@ func (f *tagsResolver) injectOAIString(schema *openapi3.Schema) {
 				schema.MaxLength = &num
 			}
 		case propPattern:
+			// invalid patterns fail at registration instead of in the consumers of the spec
+			if _, err := regexp.Compile(val); err != nil {
+				panic(fmt.Sprintf("field %s: invalid pattern %q: %v", f.f.Name, val, err))
+			}
 			schema.Pattern = val
 		case propFormat:
 			schema.Format = val
@@ -121,6 +193,30 @@ func (f *tagsResolver) injectOAIString(schema *openapi3.Schema) {
 			schema.Example = val
 		}
 	}
+
+	// Date and date-time strings accept the minimum/maximum bounds as string comparisons
+	if schema.Format == formatDate || schema.Format == formatDateTime {
+		f.injectOAIDate(schema)
+	}
+}
+
+// injectOAIDate injects the minimum/maximum OAI tags for date/date-time strings into a schema.
+// Since OpenAPI 3.0 only allows numeric bounds, they are emitted as extensions.
+func (f *tagsResolver) injectOAIDate(schema *openapi3.Schema) {
+	for tag, val := range f.pairs {
+		switch tag {
+		case propMinimum, propMin:
+			if schema.Extensions == nil {
+				schema.Extensions = make(map[string]any)
+			}
+			schema.Extensions[extFormatMinimum] = val
+		case propMaximum, propMax:
+			if schema.Extensions == nil {
+				schema.Extensions = make(map[string]any)
+			}
+			schema.Extensions[extFormatMaximum] = val
+		}
+	}
 }
 
 // injectOAINumeric injects OAI tags for numeric type into a schema.
@@ -132,6 +228,9 @@ func (f *tagsResolver) injectOAINumeric(schema *openapi3.Schema) { //nolint
 			if num, err := toFloatE(val); err == nil {
 				schema.MultipleOf = &num
 			}
+		case propFormat:
+			// overrides the format derived from the size of the Go type, e.g. an int sent as an int64
+			schema.Format = val
 		case propMinimum, propMin:
 			if num, err := toFloatE(val); err == nil {
 				schema.Min = &num
@@ -170,6 +269,14 @@ func (f *tagsResolver) injectOAINumeric(schema *openapi3.Schema) { //nolint
 			}
 		}
 	}
+
+	// the exclusive flags only modify the bounds in OpenAPI 3.0, they are meaningless on their own
+	if schema.ExclusiveMin && schema.Min == nil {
+		panic(fmt.Sprintf("field %s: %s requires %s", f.f.Name, propExclusiveMinimum, propMinimum))
+	}
+	if schema.ExclusiveMax && schema.Max == nil {
+		panic(fmt.Sprintf("field %s: %s requires %s", f.f.Name, propExclusiveMaximum, propMaximum))
+	}
 }
 
 // injectOAIBoolean injects OAI tags for boolean type into a schema.
@@ -184,6 +291,17 @@ func (f *tagsResolver) injectOAIBoolean(schema *openapi3.Schema) {
 	}
 }
 
+// injectOAIObject injects OAI tags for object type into a schema.
+// OpenAPI 3.0 has no propertyNames keyword, the constraint of the keys is stored as the x-propertyNames extension.
+func (f *tagsResolver) injectOAIObject(schema *openapi3.Schema) {
+	if pattern, ok := f.pairs[propPropertyNamesPattern]; ok && pattern != "" {
+		if schema.Extensions == nil {
+			schema.Extensions = make(map[string]any)
+		}
+		schema.Extensions[extPropertyNames] = map[string]any{"type": typeString, "pattern": pattern}
+	}
+}
+
 // injectOAIArray injects OAI tags for array type into a schema.
 func (f *tagsResolver) injectOAIArray(schema *openapi3.Schema) {
 	// Iterate over the tag pairs and inject them into the schema
@@ -199,6 +317,16 @@ func (f *tagsResolver) injectOAIArray(schema *openapi3.Schema) {
 			}
 		case propUniqueItems:
 			schema.UniqueItems = toBool(val)
+		case propDefault, propExample:
+			value, ok := toJSONComposite(val)
+			if _, isArray := value.([]any); !ok || !isArray {
+				continue
+			}
+			if tag == propDefault {
+				schema.Default = value
+			} else {
+				schema.Example = value
+			}
 		}
 	}
 }
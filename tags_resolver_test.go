@@ -1,7 +1,9 @@
 package soda_test
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/neo-f/soda/v3"
@@ -40,6 +42,31 @@ func TestTagResolver(t *testing.T) {
 			So(schema.Value.Properties["b"].Value, ShouldResemble, expectB)
 		})
 
+		Convey("It should keep escaped separators and equal signs in descriptions", func() {
+			type testStruct struct {
+				A string `json:"a" oai:"description=a\\;b=c\\;;title=T"`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["a"].Value.Description, ShouldEqual, "a;b=c;")
+			So(schema.Value.Properties["a"].Value.Title, ShouldEqual, "T")
+		})
+
+		Convey("It should keep quoted enum values containing commas", func() {
+			type testStruct struct {
+				A string `json:"a" oai:"enum='New York, NY',Paris"`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["a"].Value.Enum, ShouldResemble, []any{"New York, NY", "Paris"})
+		})
+
+		Convey("It should keep the apostrophes within unquoted enum values", func() {
+			type testStruct struct {
+				A string `json:"a" oai:"enum=don't,do,\"it's, done\""`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["a"].Value.Enum, ShouldResemble, []any{"don't", "do", "it's, done"})
+		})
+
 		Convey("It should inject string related tags", func() {
 			type testStruct struct {
 				A string `json:"a" oai:"minLength=1;maxLength=8;pattern=^\\d{1}$;format=number;enum=1,2,3;default=1;example=1;required"`
@@ -60,6 +87,88 @@ func TestTagResolver(t *testing.T) {
 			So(schema.Value, ShouldResemble, expect)
 		})
 
+		Convey("It should panic with the field name when the pattern does not compile", func() {
+			type testStruct struct {
+				Code string `json:"code" oai:"pattern=^[a-z+$"`
+			}
+			var message string
+			func() {
+				defer func() { message, _ = recover().(string) }()
+				soda.GenerateSchemaRef(testStruct{}, "json")
+			}()
+			So(message, ShouldStartWith, `field Code: invalid pattern "^[a-z+$"`)
+		})
+
+		Convey("It should override the format of the numeric types", func() {
+			type testStruct struct {
+				A int     `json:"a" oai:"format=int64"`
+				B int64   `json:"b" oai:"format=int32"`
+				C float32 `json:"c" oai:"format=double"`
+				D int32   `json:"d"`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["a"].Value.Format, ShouldEqual, "int64")
+			So(schema.Value.Properties["b"].Value.Format, ShouldEqual, "int32")
+			So(schema.Value.Properties["c"].Value.Format, ShouldEqual, "double")
+			So(schema.Value.Properties["d"].Value.Format, ShouldEqual, "int32")
+		})
+
+		Convey("It should emit the exclusive bounds along with their bounds", func() {
+			type testStruct struct {
+				A int     `json:"a" oai:"minimum=1;exclusiveMinimum"`
+				B float64 `json:"b" oai:"maximum=10;exclusiveMaximum=true"`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			a := schema.Value.Properties["a"].Value
+			So(*a.Min, ShouldEqual, 1)
+			So(a.ExclusiveMin, ShouldBeTrue)
+			b := schema.Value.Properties["b"].Value
+			So(*b.Max, ShouldEqual, 10)
+			So(b.ExclusiveMax, ShouldBeTrue)
+		})
+
+		Convey("It should panic when an exclusive flag has no bound", func() {
+			type lonelyMinimum struct {
+				A int `json:"a" oai:"exclusiveMinimum"`
+			}
+			type lonelyMaximum struct {
+				B float64 `json:"b" oai:"minimum=1;exclusiveMaximum"`
+			}
+			var message string
+			func() {
+				defer func() { message, _ = recover().(string) }()
+				soda.GenerateSchemaRef(lonelyMinimum{}, "json")
+			}()
+			So(message, ShouldEqual, "field A: exclusiveMinimum requires minimum")
+			func() {
+				defer func() { message, _ = recover().(string) }()
+				soda.GenerateSchemaRef(lonelyMaximum{}, "json")
+			}()
+			So(message, ShouldEqual, "field B: exclusiveMaximum requires maximum")
+		})
+
+		Convey("It should inject date bounds for date and date-time strings", func() {
+			type testStruct struct {
+				A string    `json:"a" oai:"format=date;minimum=2020-01-01;maximum=2030-12-31"`
+				B time.Time `json:"b" oai:"min=2020-01-01T00:00:00Z"`
+				C string    `json:"c" oai:"minimum=2020-01-01"`
+			}
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+
+			expectA := openapi3.NewStringSchema().WithFormat("date")
+			expectA.Extensions = map[string]any{
+				"x-formatMinimum": "2020-01-01",
+				"x-formatMaximum": "2030-12-31",
+			}
+			So(schema.Value.Properties["a"].Value, ShouldResemble, expectA)
+
+			expectB := openapi3.NewDateTimeSchema()
+			expectB.Extensions = map[string]any{"x-formatMinimum": "2020-01-01T00:00:00Z"}
+			So(schema.Value.Properties["b"].Value, ShouldResemble, expectB)
+
+			So(schema.Value.Properties["c"].Value, ShouldResemble, openapi3.NewStringSchema())
+		})
+
 		Convey("It should inject number related tags", func() {
 			type testStruct struct {
 				A int     `json:"a" oai:"multipleOf=1;minimum=1;exclusiveMinimum;enum=1,2,3"`
@@ -117,6 +226,179 @@ func TestTagResolver(t *testing.T) {
 		})
 	})
 
+	Convey("Given an array of structs field with a JSON example", t, func() {
+		type item struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		type testStruct struct {
+			A []item `json:"a" oai:"example=[{\"id\":1,\"name\":\"soda\"}];default=[]"`
+			B []item `json:"b" oai:"example=not json"`
+		}
+
+		Convey("It should parse the example as JSON", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			a := schema.Value.Properties["a"].Value
+			So(a.Example, ShouldResemble, []any{map[string]any{"id": 1.0, "name": "soda"}})
+			So(a.Default, ShouldResemble, []any{})
+			So(schema.Value.Properties["b"].Value.Example, ShouldBeNil)
+		})
+	})
+
+	Convey("Given object fields with JSON examples and defaults", t, func() {
+		type testStruct struct {
+			A map[string]int `json:"a" oai:"default={\"a\":1};example={\"b\":2}"`
+			B any            `json:"b" oai:"example=[1,\"two\"]"`
+			C map[string]int `json:"c" oai:"default=not json"`
+			D []int          `json:"d" oai:"example=[1,2]"`
+			E string         `json:"e" oai:"example={\"a\":1}"`
+		}
+
+		Convey("It should parse the values as JSON", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			a := schema.Value.Properties["a"].Value
+			So(a.Default, ShouldResemble, map[string]any{"a": 1.0})
+			So(a.Example, ShouldResemble, map[string]any{"b": 2.0})
+			So(schema.Value.Properties["b"].Value.Example, ShouldResemble, []any{1.0, "two"})
+			So(schema.Value.Properties["d"].Value.Example, ShouldResemble, []any{1.0, 2.0})
+		})
+
+		Convey("It should fall back to the string behavior otherwise", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["c"].Value.Default, ShouldBeNil)
+			So(schema.Value.Properties["e"].Value.Example, ShouldEqual, `{"a":1}`)
+		})
+	})
+
+	Convey("Given a deprecated field with a reason", t, func() {
+		type testStruct struct {
+			A string `json:"a" oai:"deprecated=true;deprecatedReason=use b instead"`
+			B string `json:"b"`
+		}
+
+		Convey("It should explain the deprecation in an extension", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			a := schema.Value.Properties["a"].Value
+			So(a.Deprecated, ShouldBeTrue)
+			So(a.Extensions, ShouldResemble, map[string]any{"x-deprecated-reason": "use b instead"})
+			So(schema.Value.Properties["b"].Value.Extensions, ShouldBeNil)
+		})
+	})
+
+	Convey("Given fields tagged with vendor extensions", t, func() {
+		type testStruct struct {
+			A string `json:"a" oai:"x-go-type=decimal.Decimal;x-nullable=false;x-internal"`
+			B int    `json:"b" oai:"x-order={\"rank\":1}"`
+		}
+
+		Convey("It should inject them into the schema extensions", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties["a"].Value.Extensions, ShouldResemble, map[string]any{
+				"x-go-type":  "decimal.Decimal",
+				"x-nullable": false,
+				"x-internal": true,
+			})
+			So(schema.Value.Properties["b"].Value.Extensions, ShouldResemble, map[string]any{
+				"x-order": map[string]any{"rank": 1.0},
+			})
+		})
+	})
+
+	Convey("Given a map field with constrained keys", t, func() {
+		type testStruct struct {
+			A map[string]int `json:"a" oai:"propertyNamesPattern=^[a-z]+$"`
+		}
+
+		Convey("It should inject the propertyNames schema", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			expectA := openapi3.NewObjectSchema().WithAdditionalProperties(openapi3.NewIntegerSchema())
+			expectA.Extensions = map[string]any{
+				"x-propertyNames": map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+			}
+			So(schema.Value.Properties["a"].Value, ShouldResemble, expectA)
+		})
+	})
+
+	Convey("Given fields combining omitempty, pointers, nullable and required", t, func() {
+		type matrix struct {
+			Plain                 string  `json:"plain"`
+			PlainRequired         string  `json:"plainRequired" oai:"required"`
+			PlainOptional         string  `json:"plainOptional" oai:"required=false"`
+			Pointer               *string `json:"pointer"`
+			PointerRequired       *string `json:"pointerRequired" oai:"required"`
+			OmitEmpty             string  `json:"omitEmpty,omitempty"`
+			OmitEmptyRequired     string  `json:"omitEmptyRequired,omitempty" oai:"required=true"`
+			PointerOmitEmpty      *string `json:"pointerOmitEmpty,omitempty"`
+			PointerOmitRequired   *string `json:"pointerOmitRequired,omitempty" oai:"required"`
+			Nullable              string  `json:"nullable" oai:"nullable"`
+			NullableOptional      string  `json:"nullableOptional" oai:"nullable;required=false"`
+			NullablePointer       *string `json:"nullablePointer" oai:"nullable"`
+			NullablePointerNeeded *string `json:"nullablePointerNeeded" oai:"nullable;required"`
+			NullableOmitEmpty     string  `json:"nullableOmitEmpty,omitempty" oai:"nullable"`
+			Deprecated            string  `json:"deprecated" oai:"deprecated"`
+			DeprecatedRequired    string  `json:"deprecatedRequired" oai:"deprecated;required"`
+		}
+
+		Convey("It should apply the explicit tag, then omitempty and pointers, then default to required", func() {
+			schema := soda.GenerateSchemaRef(matrix{}, "json")
+			So(schema.Value.Required, ShouldHaveLength, 9)
+			for _, name := range []string{
+				"plain", "plainRequired", "pointerRequired", "omitEmptyRequired", "pointerOmitRequired",
+				"nullable", "nullablePointerNeeded", "deprecated", "deprecatedRequired",
+			} {
+				So(schema.Value.Required, ShouldContain, name)
+			}
+		})
+
+		Convey("It should keep the explicit tag over the deprecated optional option", func() {
+			schema, err := soda.NewGenerator(soda.WithDeprecatedOptional()).GenerateSchemaRefE(matrix{}, "json")
+			So(err, ShouldBeNil)
+			So(schema.Value.Required, ShouldNotContain, "deprecated")
+			So(schema.Value.Required, ShouldContain, "deprecatedRequired")
+		})
+	})
+
+	Convey("Given slice and map fields allowed to be empty", t, func() {
+		type testStruct struct {
+			A []string       `json:"a" oai:"allowEmpty"`
+			B map[string]int `json:"b" oai:"allowEmpty"`
+			C []string       `json:"c"`
+			D []string       `json:"d" oai:"allowEmpty;required"`
+			E string         `json:"e" oai:"allowEmpty"`
+		}
+
+		Convey("It should exclude them from the required list", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Required, ShouldResemble, []string{"c", "d", "e"})
+		})
+
+		Convey("It should mark the parameters optional", func() {
+			type params struct {
+				IDs []int `query:"ids" oai:"allowEmpty;minItems=1"`
+			}
+			parameters := soda.NewGenerator().GenerateParameters(reflect.TypeOf(params{}))
+			So(parameters[0].Value.Required, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given fields omitted from the JSON when empty", t, func() {
+		type testStruct struct {
+			A string `json:"a,omitempty"`
+			B string `json:"b,string,omitempty" oai:"required=true"`
+			C string `json:"c"`
+			D string `json:"-"`
+			E int    `json:",omitempty"`
+		}
+
+		Convey("It should exclude them from the required list unless explicitly required", func() {
+			schema := soda.GenerateSchemaRef(testStruct{}, "json")
+			So(schema.Value.Properties, ShouldContainKey, "a")
+			So(schema.Value.Properties, ShouldContainKey, "E")
+			So(schema.Value.Properties, ShouldNotContainKey, "D")
+			So(schema.Value.Required, ShouldResemble, []string{"b", "c"})
+		})
+	})
+
 	Convey("Given a struct field with boolean related tags", t, func() {
 		type testStruct struct {
 			A bool `json:"a" oai:"default=true;example=false"`
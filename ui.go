@@ -1,6 +1,10 @@
 package soda
 
 import (
+	"html"
+	"io/fs"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -11,38 +15,189 @@ type UIRender interface {
 }
 
 var (
-	UISwaggerUI        = builtinUIRender{template: uiSwaggerUI}
-	UIRapiDoc          = builtinUIRender{template: uiRapiDoc}
-	UIStoplightElement = builtinUIRender{template: uiStoplightElement}
-	UIRedoc            = builtinUIRender{template: uiRedoc}
+	UISwaggerUI        = builtinUIRender{template: uiSwaggerUI, version: "3", cdn: "https://cdn.jsdelivr.net/npm/swagger-ui-dist@{:version}"}
+	UIRapiDoc          = builtinUIRender{template: uiRapiDoc, version: "latest", cdn: "https://cdn.jsdelivr.net/npm/rapidoc@{:version}/dist", attributes: rapiDocAttributes}
+	UIStoplightElement = builtinUIRender{template: uiStoplightElement, version: "latest", cdn: "https://unpkg.com/@stoplight/elements@{:version}", attributes: stoplightElementAttributes}
+	UIRedoc            = builtinUIRender{template: uiRedoc, version: "latest", cdn: "https://cdn.redoc.ly/redoc/{:version}/bundles"}
+	UIScalar           = builtinUIRender{template: uiScalar, version: "latest", cdn: "https://cdn.jsdelivr.net/npm/@scalar/api-reference@{:version}/dist/browser"}
 )
 
+// EmbeddedUIRender is a UIRender that can serve the assets provided by the application instead of loading them from a CDN.
+type EmbeddedUIRender interface {
+	UIRender
+	// Assets returns the file system holding the UI assets, or nil if there are none.
+	Assets() fs.FS
+	// RenderWithAssets renders the UI, referencing the assets under the given base path.
+	RenderWithAssets(doc *openapi3.T, base string) string
+}
+
+// UIOption is a function that configures a builtin UI render.
+type UIOption func(*builtinUIRender)
+
+// WithUIVersion pins the version of the UI assets loaded from the CDN.
+func WithUIVersion(version string) UIOption {
+	return func(u *builtinUIRender) {
+		u.version = version
+	}
+}
+
+// WithUIAssets sets the file system holding the UI assets, typically an embed.FS,
+// so that the UI can be served offline with Engine.ServeDocUIAssets.
+// The file system must contain the files of the UI distribution, e.g. swagger-ui.css and swagger-ui-bundle.js for Swagger UI.
+// soda does not bundle the distributions of the UIs, they are embedded by the application, e.g.:
+//
+//	//go:embed swagger-ui
+//	var swaggerAssets embed.FS
+//
+//	assets, _ := fs.Sub(swaggerAssets, "swagger-ui")
+//	engine.ServeDocUI("/docs", soda.NewSwaggerUI(soda.WithUIAssets(assets)))
+func WithUIAssets(assets fs.FS) UIOption {
+	return func(u *builtinUIRender) {
+		u.assets = assets
+	}
+}
+
+// WithUICDN sets the base URL the UI assets are loaded from, e.g. a private mirror of the CDN.
+// The {:version} placeholder is replaced with the version of the UI.
+func WithUICDN(cdn string) UIOption {
+	return func(u *builtinUIRender) {
+		u.cdn = cdn
+	}
+}
+
+// WithUITheme sets the theme of the UIs rendered by an element supporting one, e.g. "light" for RapiDoc.
+func WithUITheme(theme string) UIOption {
+	return WithUIAttributes(map[string]string{"theme": theme})
+}
+
+// WithUIAttributes sets attributes of the element rendering the UI, overriding the default ones,
+// e.g. "primary-color" for RapiDoc or "layout" for Stoplight Elements.
+func WithUIAttributes(attributes map[string]string) UIOption {
+	return func(u *builtinUIRender) {
+		if u.attributes == nil {
+			u.attributes = make(map[string]string, len(attributes))
+		}
+		maps.Copy(u.attributes, attributes)
+	}
+}
+
+// NewSwaggerUI creates a Swagger UI render with the given options.
+func NewSwaggerUI(opts ...UIOption) EmbeddedUIRender {
+	return newBuiltinUIRender(UISwaggerUI, opts...)
+}
+
+// NewRapiDoc creates a RapiDoc render with the given options.
+func NewRapiDoc(opts ...UIOption) EmbeddedUIRender {
+	return newBuiltinUIRender(UIRapiDoc, opts...)
+}
+
+// NewStoplightElement creates a Stoplight Elements render with the given options.
+func NewStoplightElement(opts ...UIOption) EmbeddedUIRender {
+	return newBuiltinUIRender(UIStoplightElement, opts...)
+}
+
+// NewRedoc creates a Redoc render with the given options.
+func NewRedoc(opts ...UIOption) EmbeddedUIRender {
+	return newBuiltinUIRender(UIRedoc, opts...)
+}
+
+// NewScalar creates a Scalar API reference render with the given options.
+func NewScalar(opts ...UIOption) EmbeddedUIRender {
+	return newBuiltinUIRender(UIScalar, opts...)
+}
+
+func newBuiltinUIRender(base builtinUIRender, opts ...UIOption) builtinUIRender {
+	// the default attributes are shared by the renders
+	base.attributes = maps.Clone(base.attributes)
+	for _, opt := range opts {
+		opt(&base)
+	}
+	return base
+}
+
 type builtinUIRender struct {
-	template string
-	cached   string
+	template   string
+	version    string
+	cdn        string
+	attributes map[string]string
+	assets     fs.FS
+	cached     string
 }
 
 func (u builtinUIRender) Render(doc *openapi3.T) string {
 	if u.cached == "" {
-		spec, _ := doc.MarshalJSON()
-
-		replacer := strings.NewReplacer(
-			"{:title}", doc.Info.Title,
-			"{:spec}", string(spec),
-		)
-		u.cached = replacer.Replace(u.template)
+		u.cached = u.RenderWithAssets(doc, strings.ReplaceAll(u.cdn, "{:version}", u.version))
 	}
 	return u.cached
 }
 
+func (u builtinUIRender) Assets() fs.FS {
+	return u.assets
+}
+
+func (u builtinUIRender) RenderWithAssets(doc *openapi3.T, base string) string {
+	spec, _ := doc.MarshalJSON()
+
+	replacer := strings.NewReplacer(
+		"{:title}", doc.Info.Title,
+		"{:assets}", base,
+		"{:attributes}", renderAttributes(u.attributes),
+		"{:spec}", string(spec),
+	)
+	return replacer.Replace(u.template)
+}
+
+// renderAttributes renders the attributes of an HTML element, sorted by name.
+func renderAttributes(attributes map[string]string) string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString("\n    " + name + `="` + html.EscapeString(attributes[name]) + `"`)
+	}
+	return sb.String()
+}
+
+// rapiDocAttributes are the default attributes of the RapiDoc element.
+var rapiDocAttributes = map[string]string{
+	"theme":                       "dark",
+	"primary-color":               "#f54c47",
+	"bg-color":                    "#2e3746",
+	"text-color":                  "#bacdee",
+	"default-schema-tab":          "model",
+	"allow-search":                "false",
+	"allow-advanced-search":       "true",
+	"show-info":                   "true",
+	"show-header":                 "true",
+	"show-components":             "true",
+	"schema-style":                "table",
+	"show-method-in-nav-bar":      "as-colored-block",
+	"allow-try":                   "true",
+	"allow-authentication":        "true",
+	"regular-font":                "Open Sans",
+	"mono-font":                   "Roboto Mono",
+	"font-size":                   "large",
+	"schema-description-expanded": "true",
+}
+
+// stoplightElementAttributes are the default attributes of the Stoplight Elements element.
+var stoplightElementAttributes = map[string]string{
+	"router":      "hash",
+	"hideSchemas": "true",
+}
+
 const uiSwaggerUI = `
 <!DOCTYPE html>
 <html charset="UTF-8">
 <head>
     <meta http-equiv="Content-Type" content="text/html;charset=utf-8">
     <title>{:title} Document [Swagger UI]</title>
-    <link type="text/css" rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@3/swagger-ui.css">
-    <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+    <link type="text/css" rel="stylesheet" href="{:assets}/swagger-ui.css">
+    <script src="{:assets}/swagger-ui-bundle.js"></script>
 </head>
 </html>
 <body>
@@ -67,6 +222,10 @@ const uiSwaggerUI = `
         spec: spec,
         filter: false,
         oauth2RedirectUrl: oauth2RedirectUrl,
+        operationsSorter: (a, b) => {
+            let order = (op) => op.get("operation").get("x-displayOrder") ?? Number.MAX_SAFE_INTEGER;
+            return order(a) - order(b);
+        },
     })
   </script>`
 
@@ -77,7 +236,7 @@ const uiRapiDoc = `
     <meta http-equiv="Content-Type" content="text/html;charset=utf-8">
     <meta name="viewport" content="width=device-width, minimum-scale=1, initial-scale=1, user-scalable=yes">
     <title>{:title} Document [RapiDoc]</title>
-    <script type="module" src="https://cdn.jsdelivr.net/npm/rapidoc/dist/rapidoc-min.min.js"></script>
+    <script type="module" src="{:assets}/rapidoc-min.min.js"></script>
   </head>
   <style>
     rapi-doc::part(section-navbar) { /* <<< targets navigation bar */
@@ -85,25 +244,7 @@ const uiRapiDoc = `
     }
   </style>
   <body>
-    <rapi-doc id="thedoc" 
-    theme="dark" 
-    primary-color = "#f54c47"
-    bg-color = "#2e3746"
-    text-color = "#bacdee"
-    default-schema-tab="model" 
-    allow-search="false"
-    allow-advanced-search="true"
-    show-info="true" 
-    show-header="true" 
-    show-components="true" 
-    schema-style="table"
-    show-method-in-nav-bar="as-colored-block" 
-    allow-try="true"
-    allow-authentication="true" 
-    regular-font="Open Sans" 
-    mono-font="Roboto Mono" 
-    font-size="large"
-    schema-description-expanded="true">
+    <rapi-doc id="thedoc"{:attributes}>
     </rapi-doc>
     <script>
       document.addEventListener('DOMContentLoaded', (event) => {
@@ -122,11 +263,11 @@ const uiStoplightElement = `
     <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
     <title>{:title} Document [Elements]</title>
   
-    <script src="https://unpkg.com/@stoplight/elements/web-components.min.js"></script>
-    <link rel="stylesheet" href="https://unpkg.com/@stoplight/elements/styles.min.css">
+    <script src="{:assets}/web-components.min.js"></script>
+    <link rel="stylesheet" href="{:assets}/styles.min.css">
   </head>
   <body>
-    <elements-api id="doc" router="hash" hideSchemas="true" />
+    <elements-api id="doc"{:attributes} />
   </body>
 
   <script>
@@ -156,7 +297,7 @@ const uiRedoc = `
   </head>
   <body>
     <redoc id="doc"></redoc>
-    <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"> </script>
+    <script src="{:assets}/redoc.standalone.js"> </script>
     <script>
       (async()=>{
         Redoc.init({:spec}, {}, document.getElementById('doc'))
@@ -164,3 +305,17 @@ const uiRedoc = `
     </script>
   </body>
 </html>`
+
+const uiScalar = `
+<!doctype html>
+<html>
+  <head>
+    <title>{:title} Document [Scalar]</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+  </head>
+  <body>
+    <script id="api-reference" type="application/json">{:spec}</script>
+    <script src="{:assets}/standalone.js"></script>
+  </body>
+</html>`
@@ -1,11 +1,15 @@
 package soda
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"path"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gofiber/fiber/v2"
@@ -18,7 +22,7 @@ func ptr[T any](v T) *T {
 
 // toSlice converts a string to a slice, the type of conversion is determined by the typ parameter.
 func toSlice(val string, typ string) []any {
-	ss := strings.Split(val, SeparatorPropItem)
+	ss := splitQuoted(val, SeparatorPropItem)
 	result := make([]any, 0, len(ss))
 	var transform func(string) (any, error)
 	switch typ {
@@ -39,6 +43,48 @@ func toSlice(val string, typ string) []any {
 	return result
 }
 
+// splitEscaped splits a string by the separator, ignoring the separators escaped with a backslash.
+// The escaped separators are unescaped in the result.
+func splitEscaped(val string, sep string) []string {
+	parts := strings.Split(val, sep)
+	result := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		for strings.HasSuffix(part, `\`) && i+1 < len(parts) {
+			i++
+			part = part[:len(part)-1] + sep + parts[i]
+		}
+		result = append(result, part)
+	}
+	return result
+}
+
+// splitQuoted splits a string by the separator, keeping the separators within double or single quoted items.
+// The quotes are removed from the result, e.g. `"a,b",c` is split into "a,b" and "c".
+// Only the quotes starting an item are honored, so that the apostrophes within the items are kept, e.g. don't.
+func splitQuoted(val string, sep string) []string {
+	var (
+		result []string
+		item   strings.Builder
+		quote  byte
+	)
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\'') && strings.TrimSpace(item.String()) == "":
+			quote = c
+		case quote == 0 && strings.HasPrefix(val[i:], sep):
+			result = append(result, item.String())
+			item.Reset()
+			i += len(sep) - 1
+		default:
+			item.WriteByte(c)
+		}
+	}
+	return append(result, item.String())
+}
+
 // toBool converts a string to a boolean value. If the string is empty, it returns true.
 func toBool(v string) bool {
 	if v == "" {
@@ -62,17 +108,149 @@ func toFloatE(v string) (float64, error) {
 	return strconv.ParseFloat(v, 64)
 }
 
+// toError converts a recovered panic value to an error.
+func toError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// RegisterMediaTypeAlias registers a short alias of a media type, usable in the body tag and with
+// OperationBuilder.AddResponse, e.g. RegisterMediaTypeAlias("hal", "application/hal+json").
+// It is not safe for concurrent use and should be called before the routes are registered.
+func RegisterMediaTypeAlias(alias, mediaType string) {
+	mediaTypeAliases[alias] = mediaType
+}
+
+// resolveMediaType returns the media type of the given alias, or the media type itself if it is not an alias.
+func resolveMediaType(mediaType string) (string, bool) {
+	if resolved, ok := mediaTypeAliases[mediaType]; ok {
+		return resolved, true
+	}
+	return mediaType, false
+}
+
+// isJSONMediaType checks if the media type is application/json or a structured syntax suffixed +json type.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == fiber.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// toJSONComposite parses a JSON object or array, e.g. the example of a map `oai:"example={\"a\":1}"`
+// or of an array of structs `oai:"example=[{\"id\":1}]"`.
+func toJSONComposite(val string) (any, bool) {
+	if val = strings.TrimSpace(val); !strings.HasPrefix(val, "{") && !strings.HasPrefix(val, "[") {
+		return nil, false
+	}
+	var value any
+	if err := json.Unmarshal([]byte(val), &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// toExtensionValue converts the value of a vendor extension tag: flags are true,
+// JSON values are decoded, e.g. x-nullable=false, and the other values are kept as strings.
+func toExtensionValue(val string) any {
+	if val == "" {
+		return true
+	}
+	var value any
+	if err := json.Unmarshal([]byte(val), &value); err != nil {
+		return val
+	}
+	return value
+}
+
+// toJSONValue converts a value to its generic JSON representation, such as maps and slices of any.
+// It returns the value as is if it cannot be encoded to JSON.
+func toJSONValue(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return value
+	}
+	return generic
+}
+
+// qualifyTypeArg qualifies a type argument by its package name, as the generic type itself is,
+// e.g. example.com/pkg/v2.User into pkg.User.
+func qualifyTypeArg(arg string) string {
+	i := strings.LastIndex(arg, ".")
+	if i < 0 {
+		return arg
+	}
+	return packageName(arg[:i]) + arg[i:]
+}
+
+// packageName returns the conventional name of the package with the given import path,
+// the reflected types do not expose the name of the packages of their type arguments.
+// The major version suffixes and the go- prefixes are dropped, e.g. example.com/go-pkg/v2 is named pkg,
+// and the external test packages keep their _test suffix.
+func packageName(importPath string) string {
+	importPath, test := strings.CutSuffix(importPath, "_test")
+	name := path.Base(importPath)
+	if major, ok := strings.CutPrefix(name, "v"); ok && path.Dir(importPath) != "." {
+		if _, err := strconv.Atoi(major); err == nil {
+			name = path.Base(path.Dir(importPath))
+		}
+	}
+	name = strings.TrimPrefix(name, "go-")
+	// e.g. gopkg.in/yaml.v3 is named yaml
+	if i := strings.IndexFunc(name, func(r rune) bool { return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) }); i > 0 {
+		name = name[:i]
+	}
+	if test {
+		name += "_test"
+	}
+	return name
+}
+
+// nameTagOf returns the struct tag used to name the properties of a model encoded with the given media type.
+func nameTagOf(mediaType string) string {
+	switch {
+	case strings.Contains(mediaType, "xml"):
+		return "xml"
+	case strings.Contains(mediaType, "form"):
+		return "form"
+	default:
+		return "json"
+	}
+}
+
 // genDefaultOperationID generates a default operation ID based on the method and path.
-func genDefaultOperationID(method, path string) string {
-	// Remove non-alphanumeric characters from the path
-	cleanPath := regexOperationID.ReplaceAllString(path, "-")
+// The clean ids drop the constraints of the parameters and the leading, trailing and repeated dashes.
+func genDefaultOperationID(method, path string, clean bool) string {
+	if !clean {
+		// Remove non-alphanumeric characters from the path
+		cleanPath := regexOperationID.ReplaceAllString(path, "-")
 
-	// Add the HTTP method to the front of the path
-	operationID := strings.ToLower(method) + "-" + cleanPath
+		// Add the HTTP method to the front of the path
+		return strings.ToLower(method) + "-" + cleanPath
+	}
+
+	// Drop the constraints and optional markers of the parameters, e.g. :id<int>?
+	path = regexFiberParam.ReplaceAllString(path, "$1")
+
+	// Remove non-alphanumeric characters from the path, including the leading and trailing slashes
+	cleanPath := strings.Trim(regexOperationID.ReplaceAllString(path, "-"), "-")
 
+	// Add the HTTP method to the front of the path
+	operationID := strings.ToLower(method)
+	if cleanPath != "" {
+		operationID += "-" + cleanPath
+	}
 	return operationID
 }
 
+// toTemplatePath converts the parameters of a fiber path into OpenAPI path templates, e.g. /users/:id to /users/{id}.
+func toTemplatePath(path string) string {
+	return regexFiberParam.ReplaceAllString(path, "{$1}")
+}
+
 // cleanPath cleans the path pattern, removing the regular expression constraint strings within the chi TestCase.
 func cleanPath(pattern string) string {
 	re := regexp.MustCompile(`\{(.*?):.*?\}`)
@@ -97,6 +275,49 @@ func derefSchema(doc *openapi3.T, schemaRef *openapi3.SchemaRef) *openapi3.Schem
 }
 
 // GetInput gets the input value from the http request.
+// It panics if the route has no input or if the input is not a T, see GetInputOK.
 func GetInput[T any](c *fiber.Ctx) *T {
-	return c.Locals(KeyInput).(*T)
+	stored := c.Locals(KeyInput)
+	input, ok := stored.(*T)
+	if !ok {
+		if stored == nil {
+			panic(fmt.Sprintf("soda: no input of type *%s bound, the route must be registered with SetInput", reflect.TypeFor[T]()))
+		}
+		panic(fmt.Sprintf("soda: input is of type %T, not *%s", stored, reflect.TypeFor[T]()))
+	}
+	return input
+}
+
+// GetInputOK gets the input value from the http request, reporting false instead of panicking
+// if the route has no input or if the input is not a T.
+func GetInputOK[T any](c *fiber.Ctx) (*T, bool) {
+	input, ok := c.Locals(KeyInput).(*T)
+	return input, ok
+}
+
+// StreamNDJSON streams the values received from the channel as newline delimited JSON, until the channel is closed.
+// The producer owns the channel and must close it: when the client disconnects, the remaining values are drained
+// and discarded until then, so that the producer is never blocked on a send.
+func StreamNDJSON[T any](c *fiber.Ctx, ch <-chan T) error {
+	c.Context().SetContentType(mediaTypeNDJSON)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writeNDJSON(w, ch)
+	})
+	return nil
+}
+
+// writeNDJSON writes the values received from the channel as newline delimited JSON,
+// draining the channel once a write fails.
+func writeNDJSON[T any](w *bufio.Writer, ch <-chan T) {
+	encoder := json.NewEncoder(w)
+	for v := range ch {
+		if err := encoder.Encode(v); err != nil {
+			break
+		}
+		if err := w.Flush(); err != nil {
+			break
+		}
+	}
+	for range ch {
+	}
 }
@@ -1,7 +1,10 @@
 package soda
 
 import (
+	"bufio"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/smartystreets/goconvey/convey"
 )
@@ -77,3 +80,70 @@ func TestToUint64E(t *testing.T) {
 		})
 	})
 }
+
+func TestToSlice(t *testing.T) {
+	convey.Convey("Given an enum string", t, func() {
+		convey.Convey("When the items are not quoted", func() {
+			convey.So(toSlice("a,b,c", typeString), convey.ShouldResemble, []any{"a", "b", "c"})
+		})
+
+		convey.Convey("When an item containing a comma is quoted", func() {
+			convey.So(toSlice(`"a,b",c`, typeString), convey.ShouldResemble, []any{"a,b", "c"})
+			convey.So(toSlice(`'x, y','z'`, typeString), convey.ShouldResemble, []any{"x, y", "z"})
+		})
+
+		convey.Convey("When the items are numbers", func() {
+			convey.So(toSlice(`1,"2",3`, typeInteger), convey.ShouldResemble, []any{1, 2, 3})
+		})
+	})
+}
+
+func TestToTemplatePath(t *testing.T) {
+	convey.Convey("Given fiber paths", t, func() {
+		convey.So(toTemplatePath("/users/:id"), convey.ShouldEqual, "/users/{id}")
+		convey.So(toTemplatePath("/users/:id<int>/posts/:slug?"), convey.ShouldEqual, "/users/{id}/posts/{slug}")
+		convey.So(toTemplatePath("/static/*"), convey.ShouldEqual, "/static/*")
+	})
+}
+
+func TestPackageName(t *testing.T) {
+	convey.Convey("Given import paths", t, func() {
+		convey.So(packageName("example.com/pkg"), convey.ShouldEqual, "pkg")
+		convey.So(packageName("example.com/pkg/v2"), convey.ShouldEqual, "pkg")
+		convey.So(packageName("example.com/other/v2"), convey.ShouldEqual, "other")
+		convey.So(packageName("example.com/go-pkg"), convey.ShouldEqual, "pkg")
+		convey.So(packageName("gopkg.in/yaml.v3"), convey.ShouldEqual, "yaml")
+		convey.So(packageName("github.com/neo-f/soda/v3_test"), convey.ShouldEqual, "soda_test")
+	})
+}
+
+// failingWriter fails every write, as the connection of a disconnected client.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestWriteNDJSON(t *testing.T) {
+	convey.Convey("Given a producer sending values to a disconnected client", t, func() {
+		ch := make(chan int)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer close(ch)
+			for i := 0; i < 10; i++ {
+				ch <- i
+			}
+		}()
+
+		writeNDJSON(bufio.NewWriter(failingWriter{}), ch)
+
+		convey.Convey("Then the producer should not be blocked on a send", func() {
+			finished := false
+			select {
+			case <-done:
+				finished = true
+			case <-time.After(time.Second):
+			}
+			convey.So(finished, convey.ShouldBeTrue)
+		})
+	})
+}
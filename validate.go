@@ -0,0 +1,539 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ValidationError is returned when a bound input violates the constraints documented in its schema.
+// It is reported with the 422 Unprocessable Entity status code by the fiber error handler.
+type ValidationError struct {
+	// In is the location of the invalid input, e.g. query, header or body.
+	In string `json:"in"`
+	// Name is the name of the invalid input, as in its struct tag.
+	Name string `json:"name"`
+	// Reason describes the violated constraint.
+	Reason string `json:"reason"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s parameter %q: %s", e.In, e.Name, e.Reason)
+}
+
+// Unwrap exposes the error as a fiber error, so that it is reported with the 422 status code.
+func (e *ValidationError) Unwrap() error {
+	return fiber.NewError(fiber.StatusUnprocessableEntity, e.Error())
+}
+
+// customizeValidate is implemented by the inputs validating themselves once bound, e.g. across fields.
+type customizeValidate interface {
+	Validate() error
+}
+
+// customizeValidateCtx is implemented by the inputs validating themselves once bound, with the context of the request.
+type customizeValidateCtx interface {
+	Validate(ctx context.Context) error
+}
+
+// validateInput calls the Validate method of the bound input if it has one.
+// The errors are reported with the 400 Bad Request status code, unless they are fiber errors already.
+func validateInput(c *fiber.Ctx, input any) error {
+	var err error
+	switch v := input.(type) {
+	case customizeValidate:
+		err = v.Validate()
+	case customizeValidateCtx:
+		err = v.Validate(c.UserContext())
+	}
+	if err == nil {
+		return nil
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
+	return fiber.NewError(fiber.StatusBadRequest, err.Error())
+}
+
+// inBody is the location of the ValidationErrors raised while binding the request body.
+const inBody = "body"
+
+// toValidationErrors converts an error raised while binding the input from the given location into ValidationErrors.
+// The decoding errors of the struct fields are reported under the name of their struct tag, sorted by name.
+func toValidationErrors(in string, err error) []*ValidationError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return []*ValidationError{validationErr}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		reason := fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)
+		return []*ValidationError{{In: in, Name: typeErr.Field, Reason: reason}}
+	}
+
+	// the schema decoders report the errors of the fields in a map keyed by their tag name,
+	// the one of fiber is internal and can only be walked by reflection.
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		v := reflect.ValueOf(e)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			continue
+		}
+		errs := make([]*ValidationError, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			errs = append(errs, &ValidationError{In: in, Name: key.String(), Reason: fmt.Sprint(v.MapIndex(key).Interface())})
+		}
+		slices.SortFunc(errs, func(a, b *ValidationError) int { return strings.Compare(a.Name, b.Name) })
+		return errs
+	}
+	return []*ValidationError{{In: in, Reason: err.Error()}}
+}
+
+// SpecError is returned when the generated spec is invalid.
+type SpecError struct {
+	// Method and Path identify the offending operation, they are empty if the error is not related to an operation.
+	Method string
+	Path   string
+	// Err is the error reported by the validator.
+	Err error
+}
+
+func (e *SpecError) Error() string {
+	if e.Path == "" {
+		return "invalid spec: " + e.Err.Error()
+	}
+	return fmt.Sprintf("invalid operation %s %s: %s", e.Method, e.Path, e.Err)
+}
+
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// parameterCheck binds a generated parameter to the index of the input field it is bound into.
+type parameterCheck struct {
+	index     []int
+	parameter *openapi3.Parameter
+	// format is the well-known format of the string values to validate, if any.
+	format string
+}
+
+// formatCheckers validate the string values of the well-known formats.
+var formatCheckers = map[string]func(string) bool{
+	"email": func(value string) bool {
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	},
+	"uri": func(value string) bool {
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	},
+	"uuid": regexUUID.MatchString,
+	"ipv4": func(value string) bool {
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil
+	},
+	"ipv6": func(value string) bool {
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() == nil
+	},
+	formatDate: func(value string) bool {
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	},
+	formatDateTime: func(value string) bool {
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	},
+}
+
+// collectParameterFields collects the index of the input fields bound from parameters, keyed by location and name.
+func collectParameterFields(t reflect.Type, index []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(slices.Clone(index), i)
+		if f.Tag.Get(OpenAPITag) == "-" || f.Anonymous {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				collectParameterFields(f.Type, fieldIndex, fields)
+			}
+			continue
+		}
+		for _, in := range []string{PathTag, QueryTag, HeaderTag, CookieTag} {
+			if f.Tag.Get(in) != "" {
+				fields[in+":"+newTagsResolver(f).name(in)] = fieldIndex
+				break
+			}
+		}
+	}
+}
+
+// newParameterChecks creates the bind-time checks of the array parameters generated from the input type,
+// and of the parameters of well-known formats if validateFormats is set.
+func newParameterChecks(t reflect.Type, parameters openapi3.Parameters, validateFormats bool) []parameterCheck {
+	fields := make(map[string][]int)
+	collectParameterFields(t, nil, fields)
+
+	var checks []parameterCheck
+	for _, ref := range parameters {
+		parameter := ref.Value
+		index, ok := fields[parameter.In+":"+parameter.Name]
+		if !ok || parameter.Schema == nil || parameter.Schema.Value == nil {
+			continue
+		}
+		field := t.FieldByIndex(index)
+		check := parameterCheck{index: index, parameter: parameter}
+		kind := field.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = field.Type.Elem().Kind()
+		}
+		// the formats are checked on the strings only, e.g. not on the time.Time date-time parameters
+		if validateFormats && kind == reflect.String {
+			check.format = stringFormat(parameter.Schema.Value)
+		}
+		if parameter.Schema.Value.Type.Is(typeArray) || check.format != "" {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// requiredParameter is a required query, header or cookie parameter, the path parameters are always present.
+type requiredParameter struct {
+	in   string
+	name string
+}
+
+// newRequiredParameters returns the required parameters generated from the input type.
+func newRequiredParameters(parameters openapi3.Parameters) []requiredParameter {
+	var params []requiredParameter
+	for _, ref := range parameters {
+		if parameter := ref.Value; parameter.Required && parameter.In != PathTag {
+			params = append(params, requiredParameter{in: parameter.In, name: parameter.Name})
+		}
+	}
+	return params
+}
+
+// present reports whether the parameter is sent with the request, even with an empty value.
+// The query parameters are also present as their bracketed keys, e.g. ids[] or filter[name].
+func (p requiredParameter) present(c *fiber.Ctx) bool {
+	switch p.in {
+	case HeaderTag:
+		return c.Request().Header.Peek(p.name) != nil
+	case CookieTag:
+		return c.Request().Header.Cookie(p.name) != nil
+	default:
+		present := false
+		c.Context().QueryArgs().VisitAll(func(key, _ []byte) {
+			k := string(key)
+			present = present || k == p.name || strings.HasPrefix(k, p.name+"[")
+		})
+		return present
+	}
+}
+
+// contentParameter binds a parameter documented with a content map to the index of the input field it is decoded into.
+type contentParameter struct {
+	index []int
+	in    string
+	name  string
+}
+
+// newContentParameters returns the parameters generated from the input type which are serialized as a whole,
+// with the `oai:"content=..."` tag.
+func newContentParameters(t reflect.Type, parameters openapi3.Parameters) []contentParameter {
+	fields := make(map[string][]int)
+	collectParameterFields(t, nil, fields)
+
+	var params []contentParameter
+	for _, ref := range parameters {
+		parameter := ref.Value
+		index, ok := fields[parameter.In+":"+parameter.Name]
+		if !ok || parameter.Content == nil {
+			continue
+		}
+		params = append(params, contentParameter{index: index, in: parameter.In, name: parameter.Name})
+	}
+	return params
+}
+
+// value returns the raw value of the parameter in the request.
+func (p contentParameter) value(c *fiber.Ctx) string {
+	switch p.in {
+	case PathTag:
+		return c.Params(p.name)
+	case HeaderTag:
+		return c.Get(p.name)
+	case CookieTag:
+		return c.Cookies(p.name)
+	default:
+		return c.Query(p.name)
+	}
+}
+
+// arrayParameter binds a query, header or cookie array parameter with an explicit style or explode to the index of the input field it is bound into.
+// They are bound by their serialization rather than by the EnableSplittingOnParsers setting of the app.
+type arrayParameter struct {
+	index []int
+	in    string
+	name  string
+	// split is set if the values are comma separated, i.e. not exploded with the form or simple style.
+	split bool
+}
+
+// newArrayParameters returns the array parameters generated from the input type declaring their serialization,
+// with the `oai:"style=...;explode=..."` tags.
+func newArrayParameters(t reflect.Type, parameters openapi3.Parameters) []arrayParameter {
+	fields := make(map[string][]int)
+	collectParameterFields(t, nil, fields)
+
+	var params []arrayParameter
+	for _, ref := range parameters {
+		parameter := ref.Value
+		index, ok := fields[parameter.In+":"+parameter.Name]
+		if !ok || parameter.Schema == nil || parameter.Schema.Value == nil || !parameter.Schema.Value.Type.Is(typeArray) {
+			continue
+		}
+		// the path parameters are decoded by their style when binding the path
+		if parameter.In == PathTag || parameter.Style == "" && parameter.Explode == nil {
+			continue
+		}
+		commaStyle := parameter.Style == "" || parameter.Style == openapi3.SerializationForm || parameter.Style == openapi3.SerializationSimple
+		params = append(params, arrayParameter{
+			index: index,
+			in:    parameter.In,
+			name:  parameter.Name,
+			split: commaStyle && parameter.Explode != nil && !*parameter.Explode,
+		})
+	}
+	return params
+}
+
+// values returns the values of the parameter in the request, the repeated keys being the exploded form.
+func (p arrayParameter) values(c *fiber.Ctx) []string {
+	var raw [][]byte
+	switch p.in {
+	case HeaderTag:
+		raw = c.Request().Header.PeekAll(p.name)
+	case CookieTag:
+		c.Request().Header.VisitAllCookie(func(key, value []byte) {
+			if string(key) == p.name {
+				raw = append(raw, value)
+			}
+		})
+	default:
+		raw = append(c.Context().QueryArgs().PeekMulti(p.name), c.Context().QueryArgs().PeekMulti(p.name+"[]")...)
+	}
+
+	var values []string
+	for _, value := range raw {
+		if p.split {
+			values = append(values, strings.Split(string(value), ",")...)
+		} else {
+			values = append(values, string(value))
+		}
+	}
+	return values
+}
+
+// bind binds the values of the parameter in the request into the input.
+func (p arrayParameter) bind(c *fiber.Ctx, input any) error {
+	values := p.values(c)
+	if len(values) == 0 {
+		return nil
+	}
+	return decode(p.in, input, map[string][]string{p.name: values})
+}
+
+// flagParameter binds a boolean query parameter to the index of the input field it is bound into.
+// A flag present without a value, e.g. "?active", is bound as true.
+type flagParameter struct {
+	index []int
+	name  string
+}
+
+// newFlagParameters returns the boolean query parameters generated from the input type.
+func newFlagParameters(t reflect.Type, parameters openapi3.Parameters) []flagParameter {
+	fields := make(map[string][]int)
+	collectParameterFields(t, nil, fields)
+
+	var params []flagParameter
+	for _, ref := range parameters {
+		parameter := ref.Value
+		index, ok := fields[parameter.In+":"+parameter.Name]
+		if !ok || parameter.In != QueryTag || parameter.Content != nil || parameter.Schema == nil || parameter.Schema.Value == nil || !parameter.Schema.Value.Type.Is(typeBoolean) {
+			continue
+		}
+		params = append(params, flagParameter{index: index, name: parameter.Name})
+	}
+	return params
+}
+
+// bind sets the field of the flag to true if the flag is present in the query without a value.
+func (p flagParameter) bind(c *fiber.Ctx, input any) {
+	args := c.Context().QueryArgs()
+	if !args.Has(p.name) || len(args.Peek(p.name)) > 0 {
+		return
+	}
+	field := reflect.ValueOf(input).Elem().FieldByIndex(p.index)
+	if field.Kind() == reflect.Pointer {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	if field.Kind() == reflect.Bool {
+		field.SetBool(true)
+	}
+}
+
+// pathStyle is the declared serialization of a path parameter.
+type pathStyle struct {
+	style   string
+	explode bool
+	array   bool
+}
+
+// newPathStyles returns the serialization of the path parameters declaring a style, by parameter name.
+func newPathStyles(parameters openapi3.Parameters) map[string]pathStyle {
+	styles := make(map[string]pathStyle)
+	for _, ref := range parameters {
+		parameter := ref.Value
+		if parameter.In != PathTag || parameter.Style == "" || parameter.Schema == nil || parameter.Schema.Value == nil {
+			continue
+		}
+		styles[parameter.Name] = pathStyle{
+			style:   parameter.Style,
+			explode: parameter.Explode != nil && *parameter.Explode,
+			array:   parameter.Schema.Value.Type.Is(typeArray),
+		}
+	}
+	return styles
+}
+
+// decode decodes the values of a path segment, e.g. ".1.2" with the exploded label style or ";coords=1,2" with the matrix style.
+func (s pathStyle) decode(segment string) []string {
+	var value string
+	switch s.style {
+	case openapi3.SerializationLabel:
+		value = strings.TrimPrefix(segment, ".")
+		if s.array && s.explode {
+			return strings.Split(value, ".")
+		}
+	case openapi3.SerializationMatrix:
+		if s.array && s.explode {
+			var values []string
+			for _, pair := range strings.Split(strings.TrimPrefix(segment, ";"), ";") {
+				_, v, _ := strings.Cut(pair, "=")
+				values = append(values, v)
+			}
+			return values
+		}
+		_, value, _ = strings.Cut(strings.TrimPrefix(segment, ";"), "=")
+	default:
+		value = segment
+	}
+	if s.array {
+		return strings.Split(value, ",")
+	}
+	return []string{value}
+}
+
+// dropFieldErrors removes the errors of the named fields from the map of field errors reported by the decoders.
+// It returns nil if no other field failed.
+func dropFieldErrors(err error, names []string) error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		v := reflect.ValueOf(e)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			continue
+		}
+		rest := reflect.MakeMap(v.Type())
+		for _, key := range v.MapKeys() {
+			if !slices.Contains(names, key.String()) {
+				rest.SetMapIndex(key, v.MapIndex(key))
+			}
+		}
+		switch rest.Len() {
+		case 0:
+			return nil
+		case v.Len():
+			return err
+		}
+		return rest.Interface().(error)
+	}
+	return err
+}
+
+// stringFormat returns the well-known format of the string schema.
+func stringFormat(schema *openapi3.Schema) string {
+	if _, ok := formatCheckers[schema.Format]; ok && schema.Type.Is(typeString) {
+		return schema.Format
+	}
+	return ""
+}
+
+// validate validates the bound value of the parameter against the constraints of its schema.
+func (c parameterCheck) validate(input reflect.Value) error {
+	v, err := input.FieldByIndexErr(c.index)
+	if err != nil {
+		return nil //nolint:nilerr // embedded nil pointers are left unbound
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.String {
+		return c.validateFormat(v)
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array && v.Kind() != reflect.Map {
+		return nil
+	}
+
+	schema := c.parameter.Schema.Value
+	// absent parameters are left to the required check of WithRequiredParameters
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return nil
+	}
+	if n := uint64(v.Len()); n < schema.MinItems {
+		return c.fail(fmt.Sprintf("expected at least %d items, got %d", schema.MinItems, n))
+	}
+	if n := uint64(v.Len()); schema.MaxItems != nil && n > *schema.MaxItems {
+		return c.fail(fmt.Sprintf("expected at most %d items, got %d", *schema.MaxItems, n))
+	}
+	if schema.UniqueItems {
+		for i := 0; i < v.Len(); i++ {
+			for j := i + 1; j < v.Len(); j++ {
+				if reflect.DeepEqual(v.Index(i).Interface(), v.Index(j).Interface()) {
+					return c.fail(fmt.Sprintf("duplicate items at index %d and %d", i, j))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateFormat validates a bound string value against the format of the check, empty values are left unchecked.
+func (c parameterCheck) validateFormat(v reflect.Value) error {
+	if c.format == "" || v.Kind() != reflect.String || v.Len() == 0 {
+		return nil
+	}
+	if !formatCheckers[c.format](v.String()) {
+		return c.fail(fmt.Sprintf("%q is not a valid %s", v.String(), c.format))
+	}
+	return nil
+}
+
+func (c parameterCheck) fail(reason string) error {
+	return &ValidationError{In: c.parameter.In, Name: c.parameter.Name, Reason: reason}
+}
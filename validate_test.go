@@ -0,0 +1,264 @@
+package soda_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo-f/soda/v3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParameterValidation(t *testing.T) {
+	Convey("Given an engine capturing the returned errors", t, func() {
+		var captured error
+		engine := soda.NewWith(fiber.New(fiber.Config{
+			ErrorHandler: func(c *fiber.Ctx, err error) error {
+				captured = err
+				return fiber.DefaultErrorHandler(c, err)
+			},
+		}))
+		handler := func(c *fiber.Ctx) error { return nil }
+
+		Convey("When an array parameter is tagged with uniqueItems", func() {
+			type input struct {
+				IDs  []int    `query:"ids"  oai:"uniqueItems"`
+				Tags []string `query:"tags"`
+			}
+			engine.Get("/unique", handler).SetInput(&input{}).OK()
+
+			Convey("Then duplicated items should be rejected with a ValidationError", func() {
+				request, _ := http.NewRequest("GET", "/unique?ids=1&ids=2&ids=1", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusUnprocessableEntity)
+
+				var validationErr *soda.ValidationError
+				So(errors.As(captured, &validationErr), ShouldBeTrue)
+				So(validationErr.In, ShouldEqual, "query")
+				So(validationErr.Name, ShouldEqual, "ids")
+			})
+
+			Convey("Then unique items should be accepted", func() {
+				request, _ := http.NewRequest("GET", "/unique?ids=1&ids=2&tags=a&tags=a", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When an array parameter is tagged with minItems and maxItems", func() {
+			type input struct {
+				IDs []int `query:"ids" oai:"minItems=2;maxItems=3"`
+			}
+			engine.Get("/range", handler).SetInput(&input{}).OK()
+
+			for query, code := range map[string]int{
+				"ids=1":                   http.StatusUnprocessableEntity,
+				"ids=1&ids=2":             http.StatusOK,
+				"ids=1&ids=2&ids=3":       http.StatusOK,
+				"ids=1&ids=2&ids=3&ids=4": http.StatusUnprocessableEntity,
+				"":                        http.StatusOK,
+			} {
+				request, _ := http.NewRequest("GET", "/range?"+query, nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, code)
+			}
+		})
+
+		Convey("When an array parameter is allowed to be empty", func() {
+			type input struct {
+				IDs []int `query:"ids" oai:"allowEmpty;minItems=2"`
+			}
+			engine.Get("/empty", handler).SetInput(&input{}).OK()
+
+			Convey("Then an absent parameter should be accepted", func() {
+				request, _ := http.NewRequest("GET", "/empty", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then a present parameter should still be validated", func() {
+				request, _ := http.NewRequest("GET", "/empty?ids=1", nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusUnprocessableEntity)
+			})
+		})
+	})
+
+	Convey("Given an engine validating the formats", t, func() {
+		var captured error
+		engine := soda.NewWith(fiber.New(fiber.Config{
+			ErrorHandler: func(c *fiber.Ctx, err error) error {
+				captured = err
+				return fiber.DefaultErrorHandler(c, err)
+			},
+		}), soda.WithFormatValidation())
+		type input struct {
+			Email string  `query:"email" oai:"format=email"`
+			ID    *string `query:"id"    oai:"format=uuid"`
+			Site  string  `query:"site"  oai:"format=uri"`
+		}
+		engine.Get("/formats", func(c *fiber.Ctx) error { return nil }).SetInput(&input{}).OK()
+
+		for query, name := range map[string]string{
+			"email=not-an-email":       "email",
+			"email=a@b.c&id=123":       "id",
+			"email=a@b.c&site=example": "site",
+		} {
+			Convey("Then an invalid value should be rejected: "+query, func() {
+				request, _ := http.NewRequest("GET", "/formats?"+query, nil)
+				response, err := engine.App().Test(request)
+				So(err, ShouldBeNil)
+				So(response.StatusCode, ShouldEqual, http.StatusUnprocessableEntity)
+
+				var validationErr *soda.ValidationError
+				So(errors.As(captured, &validationErr), ShouldBeTrue)
+				So(validationErr.Name, ShouldEqual, name)
+			})
+		}
+
+		Convey("Then valid values should be accepted", func() {
+			request, _ := http.NewRequest("GET", "/formats?email=a@b.c&id=6ba7b810-9dad-11d1-80b4-00c04fd430c8&site=https://a.b", nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, http.StatusOK)
+		})
+
+		Convey("Then the parameters of well-known formats which are not strings should be bound", func() {
+			type timeInput struct {
+				Since time.Time `query:"since"`
+			}
+			engine.Get("/since", func(c *fiber.Ctx) error {
+				return c.SendString(soda.GetInput[timeInput](c).Since.UTC().Format(time.RFC3339))
+			}).SetInput(&timeInput{}).OK()
+			request, _ := http.NewRequest("GET", "/since?since=2024-01-01T00:00:00Z", nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, http.StatusOK)
+			body, _ := io.ReadAll(response.Body)
+			So(string(body), ShouldEqual, "2024-01-01T00:00:00Z")
+		})
+
+		Convey("Then the formats should not be validated without the option", func() {
+			engine := soda.New()
+			engine.Get("/formats", func(c *fiber.Ctx) error { return nil }).SetInput(&input{}).OK()
+			request, _ := http.NewRequest("GET", "/formats?email=not-an-email", nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, http.StatusOK)
+		})
+	})
+
+	Convey("Given an engine checking the required parameters", t, func() {
+		var captured error
+		engine := soda.NewWith(fiber.New(fiber.Config{
+			ErrorHandler: func(c *fiber.Ctx, err error) error {
+				captured = err
+				return fiber.DefaultErrorHandler(c, err)
+			},
+		}), soda.WithRequiredParameters())
+		type input struct {
+			Limit int    `query:"limit"`
+			Tags  []int  `query:"tags"  oai:"allowEmpty;minItems=1"`
+			Token string `header:"X-Token"`
+			Page  *int   `query:"page"`
+		}
+		engine.Get("/required", func(c *fiber.Ctx) error { return nil }).SetInput(&input{}).OK()
+		get := func(query string, token bool) int {
+			request, _ := http.NewRequest("GET", "/required?"+query, nil)
+			if token {
+				request.Header.Set("X-Token", "abc")
+			}
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			return response.StatusCode
+		}
+
+		Convey("Then an absent required parameter should be rejected", func() {
+			So(get("", true), ShouldEqual, http.StatusUnprocessableEntity)
+			So(captured, ShouldBeError, `invalid query parameter "limit": is required`)
+			So(get("limit=1", false), ShouldEqual, http.StatusUnprocessableEntity)
+			So(captured, ShouldBeError, `invalid header parameter "X-Token": is required`)
+		})
+
+		Convey("Then the optional parameters allowed to be empty may be absent", func() {
+			So(get("limit=1", true), ShouldEqual, http.StatusOK)
+		})
+
+		Convey("Then the bracketed keys should make a parameter present", func() {
+			So(get("limit=1&tags=1", true), ShouldEqual, http.StatusOK)
+			So(get("limit=1&tags[]=1", true), ShouldEqual, http.StatusOK)
+		})
+
+		Convey("Then absent parameters should be accepted without the option", func() {
+			engine := soda.New()
+			engine.Get("/required", func(c *fiber.Ctx) error { return nil }).SetInput(&input{}).OK()
+			request, _ := http.NewRequest("GET", "/required", nil)
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestValidationErrorHandler(t *testing.T) {
+	Convey("Given an engine reporting the validation errors as JSON", t, func() {
+		engine := soda.New(soda.WithValidationErrorHandler(func(c *fiber.Ctx, errs []*soda.ValidationError) error {
+			return c.Status(fiber.StatusBadRequest).JSON(errs)
+		}))
+		type body struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}
+		type input struct {
+			Limit int   `query:"limit"`
+			IDs   []int `query:"ids"    oai:"uniqueItems"`
+			Token int   `header:"X-Token"`
+			Body  body  `body:"json"`
+		}
+		engine.Post("/items", func(c *fiber.Ctx) error { return nil }).SetInput(&input{}).OK()
+
+		post := func(query, body string) (int, []map[string]any) {
+			request, _ := http.NewRequest("POST", "/items?"+query, strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("X-Token", "abc")
+			response, err := engine.App().Test(request)
+			So(err, ShouldBeNil)
+			var errs []map[string]any
+			_ = json.NewDecoder(response.Body).Decode(&errs)
+			return response.StatusCode, errs
+		}
+
+		Convey("When posting invalid input in several locations", func() {
+			code, errs := post("limit=ten&ids=1&ids=1", `{"name":"soda","count":"one"}`)
+
+			Convey("Then every error should be reported under the name of its struct tag", func() {
+				So(code, ShouldEqual, http.StatusBadRequest)
+				So(errs, ShouldHaveLength, 4)
+				So(errs[0]["in"], ShouldEqual, "header")
+				So(errs[0]["name"], ShouldEqual, "X-Token")
+				So(errs[1]["in"], ShouldEqual, "query")
+				So(errs[1]["name"], ShouldEqual, "limit")
+				So(errs[2], ShouldResemble, map[string]any{
+					"in":     "query",
+					"name":   "ids",
+					"reason": "duplicate items at index 0 and 1",
+				})
+				So(errs[3], ShouldResemble, map[string]any{
+					"in":     "body",
+					"name":   "count",
+					"reason": "expected int, got string",
+				})
+			})
+		})
+	})
+}